@@ -0,0 +1,24 @@
+package database
+
+import (
+	"log"
+)
+
+// protectAuditLog installs triggers that make the audit_logs table
+// append-only, so not even a buggy handler can rewrite history.
+func protectAuditLog() {
+	statements := []string{
+		`CREATE TRIGGER IF NOT EXISTS audit_logs_no_update BEFORE UPDATE ON audit_logs BEGIN
+			SELECT RAISE(ABORT, 'audit log is append-only');
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS audit_logs_no_delete BEFORE DELETE ON audit_logs BEGIN
+			SELECT RAISE(ABORT, 'audit log is append-only');
+		END`,
+	}
+
+	for _, statement := range statements {
+		if err := DB.Exec(statement).Error; err != nil {
+			log.Fatal("Failed to protect audit log:", err)
+		}
+	}
+}
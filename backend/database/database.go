@@ -2,6 +2,7 @@ package database
 
 import (
 	"log"
+	"time"
 	"uf-marketplace/models"
 
 	"gorm.io/driver/sqlite"
@@ -20,6 +21,19 @@ func InitDB() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// Accounts created before email verification existed are trusted
+	grandfatherUsers := DB.Migrator().HasTable(&models.User{}) &&
+		!DB.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt")
+
+	// Admins from before roles existed keep their access
+	migrateAdmins := DB.Migrator().HasTable(&models.User{}) &&
+		!DB.Migrator().HasColumn(&models.User{}, "Role") &&
+		DB.Migrator().HasColumn(&models.User{}, "is_admin")
+
+	// Categories seeded before listing expiry existed get the seeded lifetimes
+	addLifetimes := DB.Migrator().HasTable(&models.Category{}) &&
+		!DB.Migrator().HasColumn(&models.Category{}, "ListingLifetimeDays")
+
 	// Auto migrate models
 	err = DB.AutoMigrate(
 		&models.User{},
@@ -29,33 +43,97 @@ func InitDB() {
 		&models.Chat{},
 		&models.Message{},
 		&models.Notification{},
+		&models.Offer{},
+		&models.Favorite{},
+		&models.Upload{},
+		&models.Session{},
+		&models.PasswordReset{},
+		&models.AuditLog{},
+		&models.Report{},
+		&models.Review{},
+		&models.Transaction{},
+		&models.ListingStatusChange{},
+		&models.SavedSearch{},
+		&models.SavedSearchMatch{},
+		&models.UserBlock{},
+		&models.LoginAttempt{},
+		&models.RecoveryCode{},
+		&models.StreamTicket{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	if grandfatherUsers {
+		DB.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL")
+	}
+
+	if migrateAdmins {
+		DB.Exec("UPDATE users SET role = ? WHERE is_admin = ?", models.RoleAdmin, true)
+	}
+
+	// Status used to be free text; park anything unknown as inactive
+	DB.Model(&models.Listing{}).
+		Where("status NOT IN ?", []models.ListingStatus{models.StatusActive, models.StatusReserved,
+			models.StatusPending, models.StatusSold, models.StatusInactive, models.StatusRemoved}).
+		Update("status", models.StatusInactive)
+
+	// Full-text search index for listings
+	setupListingSearch()
+
+	// Admin audit log can only be appended to
+	protectAuditLog()
+
 	// Seed categories if they don't exist
-	seedCategories()
+	seedCategories(addLifetimes)
+
+	// Listings from before expiry existed need an expiry date
+	backfillListingExpiry()
 
 	log.Println("Database initialized successfully")
 }
 
-func seedCategories() {
+// seedCategories creates the default categories. addLifetimes also sets
+// the listing lifetime of categories seeded before expiry existed.
+func seedCategories(addLifetimes bool) {
 	categories := []models.Category{
-		{Name: "Textbooks", Description: "Academic textbooks and study materials", Icon: "book"},
-		{Name: "Electronics", Description: "Phones, laptops, tablets, and accessories", Icon: "devices"},
-		{Name: "Furniture", Description: "Dorm and apartment furniture", Icon: "chair"},
-		{Name: "Clothing", Description: "Clothes, shoes, and accessories", Icon: "checkroom"},
-		{Name: "Sports", Description: "Sports equipment and gear", Icon: "sports_soccer"},
-		{Name: "Tickets", Description: "Event and game tickets", Icon: "confirmation_number"},
-		{Name: "Transportation", Description: "Bikes, scooters, and car accessories", Icon: "directions_bike"},
-		{Name: "Services", Description: "Tutoring, moving help, etc.", Icon: "handyman"},
-		{Name: "Housing", Description: "Sublease and roommate listings", Icon: "home"},
-		{Name: "Other", Description: "Everything else", Icon: "category"},
+		{Name: "Textbooks", Description: "Academic textbooks and study materials", Icon: "book", ListingLifetimeDays: 120},
+		{Name: "Electronics", Description: "Phones, laptops, tablets, and accessories", Icon: "devices", ListingLifetimeDays: 45},
+		{Name: "Furniture", Description: "Dorm and apartment furniture", Icon: "chair", ListingLifetimeDays: 60},
+		{Name: "Clothing", Description: "Clothes, shoes, and accessories", Icon: "checkroom", ListingLifetimeDays: 45},
+		{Name: "Sports", Description: "Sports equipment and gear", Icon: "sports_soccer", ListingLifetimeDays: 60},
+		{Name: "Tickets", Description: "Event and game tickets", Icon: "confirmation_number", ListingLifetimeDays: 14},
+		{Name: "Transportation", Description: "Bikes, scooters, and car accessories", Icon: "directions_bike", ListingLifetimeDays: 60},
+		{Name: "Services", Description: "Tutoring, moving help, etc.", Icon: "handyman", ListingLifetimeDays: 90},
+		{Name: "Housing", Description: "Sublease and roommate listings", Icon: "home", ListingLifetimeDays: 60},
+		{Name: "Other", Description: "Everything else", Icon: "category", ListingLifetimeDays: 30},
 	}
 
 	for _, category := range categories {
+		lifetime := category.ListingLifetimeDays
 		DB.FirstOrCreate(&category, models.Category{Name: category.Name})
+		if addLifetimes {
+			DB.Model(&category).Update("listing_lifetime_days", lifetime)
+		}
+	}
+}
+
+// backfillListingExpiry gives listings created before expiry existed an
+// expiry date from their category, with at least a week's notice so
+// sellers aren't surprised by a wave of expirations.
+func backfillListingExpiry() {
+	var listings []models.Listing
+	DB.Preload("Category").
+		Where("expires_at IS NULL AND status IN ?", []models.ListingStatus{models.StatusActive, models.StatusReserved}).
+		Find(&listings)
+
+	earliest := time.Now().Add(7 * 24 * time.Hour)
+	for _, listing := range listings {
+		expiresAt := listing.CreatedAt.Add(listing.Category.ListingLifetime())
+		if expiresAt.Before(earliest) {
+			expiresAt = earliest
+		}
+		DB.Model(&listing).Update("expires_at", expiresAt)
 	}
 }
 
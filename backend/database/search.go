@@ -0,0 +1,100 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// FTSEnabled is true when the SQLite build supports FTS5 and the listing
+// search index is in place. The binary has to be built with
+// `-tags sqlite_fts5` for this; otherwise search falls back to LIKE.
+var FTSEnabled bool
+
+// listingSearchTriggers are the triggers setupListingSearch creates on listings.
+var listingSearchTriggers = []string{"listings_fts_insert", "listings_fts_delete", "listings_fts_update"}
+
+// setupListingSearch creates the listings_fts index and the triggers that
+// keep it in sync with the listings table. Soft-deleted listings are removed
+// from the index, and only title, description and deleted_at changes touch
+// it so view-count bumps stay cheap.
+func setupListingSearch() {
+	// A binary built without FTS5 can still be pointed at a database an FTS5
+	// build set up, and the triggers left behind would then fail every write
+	// to listings. Check the module is really there before trusting the table.
+	var fts5 bool
+	err := DB.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error
+	if err != nil || !fts5 {
+		log.Printf("Full-text search unavailable (build with -tags sqlite_fts5), falling back to LIKE")
+		dropListingSearchTriggers()
+		return
+	}
+
+	var existing, triggered int64
+	DB.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'listings_fts'").Scan(&existing)
+	DB.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ?", listingSearchTriggers).Scan(&triggered)
+
+	err = DB.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS listings_fts USING fts5(
+		title,
+		description,
+		content='listings',
+		content_rowid='id',
+		tokenize='porter unicode61'
+	)`).Error
+	if err != nil {
+		log.Printf("Full-text search unavailable, falling back to LIKE: %v", err)
+		return
+	}
+
+	triggers := []string{
+		`CREATE TRIGGER IF NOT EXISTS listings_fts_insert AFTER INSERT ON listings
+		WHEN new.deleted_at IS NULL BEGIN
+			INSERT INTO listings_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS listings_fts_delete AFTER DELETE ON listings
+		WHEN old.deleted_at IS NULL BEGIN
+			INSERT INTO listings_fts(listings_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS listings_fts_update AFTER UPDATE OF title, description, deleted_at ON listings BEGIN
+			INSERT INTO listings_fts(listings_fts, rowid, title, description)
+				SELECT 'delete', old.id, old.title, old.description WHERE old.deleted_at IS NULL;
+			INSERT INTO listings_fts(rowid, title, description)
+				SELECT new.id, new.title, new.description WHERE new.deleted_at IS NULL;
+		END`,
+	}
+	for _, trigger := range triggers {
+		if err := DB.Exec(trigger).Error; err != nil {
+			log.Printf("Failed to create search trigger, falling back to LIKE: %v", err)
+			dropListingSearchTriggers()
+			return
+		}
+	}
+
+	// Index listings that existed before the search table did, or that were
+	// written while the triggers were missing
+	if existing == 0 || triggered < int64(len(listingSearchTriggers)) {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("INSERT INTO listings_fts(listings_fts) VALUES ('delete-all')").Error; err != nil {
+				return err
+			}
+			return tx.Exec(`INSERT INTO listings_fts(rowid, title, description)
+				SELECT id, title, description FROM listings WHERE deleted_at IS NULL`).Error
+		})
+		if err != nil {
+			log.Printf("Failed to build search index, falling back to LIKE: %v", err)
+			return
+		}
+	}
+
+	FTSEnabled = true
+}
+
+// dropListingSearchTriggers removes the triggers that feed listings_fts, so
+// listings can still be written when the index can't be maintained.
+func dropListingSearchTriggers() {
+	for _, name := range listingSearchTriggers {
+		if err := DB.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+			log.Printf("Failed to drop search trigger %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/jobs"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetOrphanedUploads reports how much space the upload sweeper would
+// reclaim right now, without deleting anything.
+func GetOrphanedUploads(c *gin.Context) {
+	grace, ok := sweepGracePeriod(c)
+	if !ok {
+		return
+	}
+
+	report, err := jobs.SweepUploads(c.Request.Context(), grace, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error scanning uploads"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// SweepOrphanedUploads runs the upload sweeper immediately. Pass
+// dry_run=true to preview.
+func SweepOrphanedUploads(c *gin.Context) {
+	grace, ok := sweepGracePeriod(c)
+	if !ok {
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if !dryRun && grace < jobs.MinUploadGracePeriod {
+		queryErrors{{Field: "grace", Message: "must be at least " + jobs.MinUploadGracePeriod.String() +
+			" unless dry_run is set"}}.respond(c)
+		return
+	}
+
+	report, err := jobs.SweepUploads(c.Request.Context(), grace, dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error sweeping uploads"})
+		return
+	}
+
+	if !dryRun {
+		recordAudit(database.DB, c, models.AuditUploadsSweep, "uploads", 0, "",
+			gin.H{"grace_period": report.GracePeriod, "deleted": report.Deleted, "bytes": report.Bytes})
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// sweepGracePeriod reads the optional "grace" duration param, defaulting to
+// the sweeper's configured grace period.
+func sweepGracePeriod(c *gin.Context) (time.Duration, bool) {
+	raw := c.Query("grace")
+	if raw == "" {
+		return jobs.UploadGracePeriod, true
+	}
+	grace, err := time.ParseDuration(raw)
+	if err != nil || grace < 0 {
+		queryErrors{{Field: "grace", Message: "must be a duration such as 24h"}}.respond(c)
+		return 0, false
+	}
+	return grace, true
+}
+
+type RemoveListingInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type RestoreListingInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type CategoryInput struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	// Days listings stay up before expiring; 0 uses the default
+	ListingLifetimeDays int `json:"listing_lifetime_days" binding:"min=0,max=365"`
+}
+
+// RemoveListing takes a listing down for breaking the rules. The seller is
+// told why, and only an admin can bring it back.
+func RemoveListing(c *gin.Context) {
+	listing, ok := loadListingParam(c)
+	if !ok {
+		return
+	}
+
+	var input RemoveListingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	if listing.Status == models.StatusRemoved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This listing has already been removed"})
+		return
+	}
+
+	previous := listing.Status
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&listing).Update("moderation_reason", input.Reason).Error; err != nil {
+			return err
+		}
+		if err := setListingStatus(tx, &listing, models.StatusRemoved, models.ActorAdmin, c.GetUint("userID"), input.Reason); err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditListingRemove, "listing", listing.ID, input.Reason,
+			gin.H{"title": listing.Title, "seller_id": listing.SellerID, "previous_status": previous})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error removing listing"})
+		return
+	}
+
+	notify.Send(listing.SellerID, models.NotificationListingRemoved, "Listing Removed",
+		"Your listing \""+listing.Title+"\" was removed by a moderator: "+input.Reason,
+		notify.ListingLink(listing.ID))
+
+	database.DB.Preload("Images").Preload("Category").Preload("Seller").First(&listing, listing.ID)
+
+	c.JSON(http.StatusOK, listing)
+}
+
+// RestoreListing shows a listing that reports hid again. Dismissing its
+// reports does this on its own, but once a report has been actioned the
+// listing stays hidden until a moderator restores or removes it.
+func RestoreListing(c *gin.Context) {
+	listing, ok := loadListingParam(c)
+	if !ok {
+		return
+	}
+
+	var input RestoreListingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	if listing.Status == models.StatusRemoved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This listing has been removed"})
+		return
+	}
+	if listing.HiddenAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This listing isn't hidden"})
+		return
+	}
+
+	hiddenAt := *listing.HiddenAt
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&listing).Update("hidden_at", nil).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditListingRestore, "listing", listing.ID, input.Reason,
+			gin.H{"title": listing.Title, "seller_id": listing.SellerID, "hidden_at": hiddenAt})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error restoring listing"})
+		return
+	}
+
+	notify.Send(listing.SellerID, models.NotificationListingStatus, "Listing Restored",
+		"Your listing \""+listing.Title+"\" is visible again after moderator review",
+		notify.ListingLink(listing.ID))
+
+	database.DB.Preload("Images").Preload("Category").Preload("Seller").First(&listing, listing.ID)
+
+	c.JSON(http.StatusOK, listing)
+}
+
+func CreateCategory(c *gin.Context) {
+	var input CategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category name is required and listing_lifetime_days must be 0-365"})
+		return
+	}
+
+	category := models.Category{
+		Name:        strings.TrimSpace(input.Name),
+		Description: input.Description,
+		Icon:        input.Icon,
+
+		ListingLifetimeDays: input.ListingLifetimeDays,
+	}
+
+	if categoryNameTaken(category.Name, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A category with this name already exists"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&category).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditCategoryCreate, "category", category.ID, "", gin.H{"category": category})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating category"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+func UpdateCategory(c *gin.Context) {
+	category, ok := loadCategoryParam(c)
+	if !ok {
+		return
+	}
+
+	var input CategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category name is required and listing_lifetime_days must be 0-365"})
+		return
+	}
+
+	before := category
+	category.Name = strings.TrimSpace(input.Name)
+	category.Description = input.Description
+	category.Icon = input.Icon
+	category.ListingLifetimeDays = input.ListingLifetimeDays
+
+	if categoryNameTaken(category.Name, category.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A category with this name already exists"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&category).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditCategoryUpdate, "category", category.ID, "",
+			gin.H{"before": before, "after": category})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory removes an empty category. Listings have to be moved out
+// first so nothing is left pointing at it.
+func DeleteCategory(c *gin.Context) {
+	category, ok := loadCategoryParam(c)
+	if !ok {
+		return
+	}
+
+	var listingCount int64
+	database.DB.Model(&models.Listing{}).Where("category_id = ?", category.ID).Count(&listingCount)
+	if listingCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This category still has listings"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Hard delete so the unique name can be reused
+		if err := tx.Unscoped().Delete(&category).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditCategoryDelete, "category", category.ID, "", gin.H{"category": category})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
+
+// GetAdminStats returns platform-wide counts for the admin dashboard.
+func GetAdminStats(c *gin.Context) {
+	weekAgo := time.Now().AddDate(0, 0, -7)
+	now := time.Now()
+
+	count := func(model interface{}, query string, args ...interface{}) int64 {
+		var total int64
+		q := database.DB.Model(model)
+		if query != "" {
+			q = q.Where(query, args...)
+		}
+		q.Count(&total)
+		return total
+	}
+
+	listingsByStatus := gin.H{}
+	for _, status := range []models.ListingStatus{models.StatusActive, models.StatusReserved, models.StatusPending,
+		models.StatusSold, models.StatusInactive, models.StatusRemoved} {
+		listingsByStatus[string(status)] = count(&models.Listing{}, "status = ?", status)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": gin.H{
+			"total":      count(&models.User{}, ""),
+			"new_7d":     count(&models.User{}, "created_at > ?", weekAgo),
+			"unverified": count(&models.User{}, "email_verified_at IS NULL"),
+			"suspended":  count(&models.User{}, "banned_at IS NULL AND suspended_until > ?", now),
+			"banned":     count(&models.User{}, "banned_at IS NOT NULL"),
+		},
+		"listings": gin.H{
+			"total":     count(&models.Listing{}, ""),
+			"new_7d":    count(&models.Listing{}, "created_at > ?", weekAgo),
+			"by_status": listingsByStatus,
+			"hidden":    count(&models.Listing{}, "hidden_at IS NOT NULL"),
+		},
+		"open_reports": count(&models.Report{}, "status IN ?",
+			[]models.ReportStatus{models.ReportOpen, models.ReportReviewing}),
+		"chats":    count(&models.Chat{}, ""),
+		"messages": count(&models.Message{}, ""),
+		"offers":   count(&models.Offer{}, ""),
+	})
+}
+
+// GetAuditLog pages through admin actions newest first, filterable by
+// actor, action and target.
+func GetAuditLog(c *gin.Context) {
+	var errs queryErrors
+	limit := errs.queryInt(c, "limit", defaultAdminPageLimit, 1, maxAdminPageLimit)
+	cursor, _ := errs.queryCursor(c)
+	actorID, hasActor := errs.queryID(c, "actor_id")
+	targetID, hasTarget := errs.queryID(c, "target_id")
+	if errs.respond(c) {
+		return
+	}
+
+	query := database.DB.Preload("Actor")
+	if hasActor {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if hasTarget {
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	order := keyset{IDColumn: "id", Desc: true}
+
+	var entries []models.AuditLog
+	if result := order.apply(query, cursor, nil).Limit(limit + 1).Find(&entries); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching audit log"})
+		return
+	}
+
+	entries, hasNext, hasPrev := trimPage(entries, limit, cursor)
+
+	response := gin.H{"entries": entries}
+	if len(entries) > 0 {
+		setIDCursors(response, entries[0].ID, entries[len(entries)-1].ID, hasNext, hasPrev)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// recordAudit appends an admin action to the audit log. Pass the
+// transaction the action ran in so the two commit or fail together.
+func recordAudit(db *gorm.DB, c *gin.Context, action models.AuditAction, targetType string, targetID uint, reason string, details gin.H) error {
+	entry := models.AuditLog{
+		ActorID:    c.GetUint("userID"),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		IPAddress:  c.ClientIP(),
+	}
+	if details != nil {
+		encoded, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		entry.Details = string(encoded)
+	}
+	return db.Create(&entry).Error
+}
+
+// loadCategoryParam resolves the :id route param to a category.
+func loadCategoryParam(c *gin.Context) (models.Category, bool) {
+	var category models.Category
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return category, false
+	}
+
+	if result := database.DB.First(&category, id); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return category, false
+	}
+
+	return category, true
+}
+
+// categoryNameTaken reports whether another category already uses name,
+// ignoring case.
+func categoryNameTaken(name string, exceptID uint) bool {
+	var count int64
+	database.DB.Model(&models.Category{}).Where("LOWER(name) = LOWER(?) AND id != ?", name, exceptID).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/mailer"
+	"uf-marketplace/middleware"
+	"uf-marketplace/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAdminPageLimit = 50
+	maxAdminPageLimit     = 200
+)
+
+// adminUserStatuses are the accepted values for the "status" filter.
+var adminUserStatuses = []string{"active", "suspended", "banned", "unverified"}
+
+// AdminUserResponse is a user as admins see them, including moderation state.
+type AdminUserResponse struct {
+	models.UserResponse
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	BannedAt         *time.Time `json:"banned_at,omitempty"`
+	ModerationReason string     `json:"moderation_reason,omitempty"`
+	FailedLogins     int64      `json:"failed_logins"`
+	LockedUntil      *time.Time `json:"locked_until,omitempty"` // sign-in lockout or backoff
+	ListingCount     int64      `json:"listing_count"`
+}
+
+type SuspendUserInput struct {
+	Days   int    `json:"days" binding:"required,min=1,max=365"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+type ModerationInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type SetRoleInput struct {
+	Role   models.Role `json:"role" binding:"required"`
+	Reason string      `json:"reason"`
+}
+
+func toAdminUser(user models.User) AdminUserResponse {
+	response := AdminUserResponse{
+		UserResponse:     user.ToResponse(),
+		BannedAt:         user.BannedAt,
+		ModerationReason: user.ModerationReason,
+	}
+	if user.IsSuspended() {
+		response.SuspendedUntil = user.SuspendedUntil
+	}
+	failures, lockedUntil := accountLoginLimit.wait(user.Email)
+	response.FailedLogins = failures
+	if time.Now().Before(lockedUntil) {
+		response.LockedUntil = &lockedUntil
+	}
+	database.DB.Model(&models.Listing{}).Where("seller_id = ?", user.ID).Count(&response.ListingCount)
+	return response
+}
+
+// AdminGetUsers lists users newest first, optionally filtered by a search
+// over name and email, by account status and by role.
+func AdminGetUsers(c *gin.Context) {
+	var errs queryErrors
+	limit := errs.queryInt(c, "limit", defaultAdminPageLimit, 1, maxAdminPageLimit)
+	cursor, _ := errs.queryCursor(c)
+	status := c.Query("status")
+	if status != "" && !slices.Contains(adminUserStatuses, status) {
+		errs.add("status", "must be one of "+strings.Join(adminUserStatuses, ", "))
+	}
+	role := models.Role(c.Query("role"))
+	if role != "" && !role.IsValid() {
+		errs.add("role", "must be user, moderator or admin")
+	}
+	if errs.respond(c) {
+		return
+	}
+
+	query := database.DB.Model(&models.User{})
+
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		pattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(email) LIKE ? OR LOWER(first_name || ' ' || last_name) LIKE ?", pattern, pattern)
+	}
+
+	now := time.Now()
+	switch status {
+	case "active":
+		query = query.Where("banned_at IS NULL AND (suspended_until IS NULL OR suspended_until <= ?)", now)
+	case "suspended":
+		query = query.Where("banned_at IS NULL AND suspended_until > ?", now)
+	case "banned":
+		query = query.Where("banned_at IS NOT NULL")
+	case "unverified":
+		query = query.Where("email_verified_at IS NULL")
+	}
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	order := keyset{IDColumn: "id", Desc: true}
+
+	var users []models.User
+	if result := order.apply(query, cursor, nil).Limit(limit + 1).Find(&users); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching users"})
+		return
+	}
+
+	users, hasNext, hasPrev := trimPage(users, limit, cursor)
+
+	responses := make([]AdminUserResponse, len(users))
+	for i, user := range users {
+		responses[i] = toAdminUser(user)
+	}
+
+	response := gin.H{"users": responses}
+	if len(users) > 0 {
+		setIDCursors(response, users[0].ID, users[len(users)-1].ID, hasNext, hasPrev)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func AdminGetUser(c *gin.Context) {
+	user, ok := loadUserParam(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toAdminUser(user))
+}
+
+// SuspendUser locks an account out for a number of days and signs it out
+// everywhere.
+func SuspendUser(c *gin.Context) {
+	user, ok := loadModeratedUser(c)
+	if !ok {
+		return
+	}
+
+	var input SuspendUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason and a suspension length of 1-365 days are required"})
+		return
+	}
+
+	until := time.Now().AddDate(0, 0, input.Days)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"suspended_until":   until,
+			"moderation_reason": input.Reason,
+		}).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditUserSuspend, "user", user.ID, input.Reason,
+			gin.H{"days": input.Days, "suspended_until": until})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error suspending user"})
+		return
+	}
+
+	revokeUserSessions(user.ID, 0)
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Your UF Market account has been suspended",
+		Body: "Hi " + user.FirstName + ",\n\n" +
+			"Your UF Market account has been suspended until " + until.Format("January 2, 2006") + ".\n\n" +
+			"Reason: " + input.Reason + "\n",
+	})
+
+	c.JSON(http.StatusOK, toAdminUser(user))
+}
+
+// BanUser permanently locks an account out and takes down its active
+// listings.
+func BanUser(c *gin.Context) {
+	user, ok := loadModeratedUser(c)
+	if !ok {
+		return
+	}
+
+	var input ModerationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	var removed int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"banned_at":         time.Now(),
+			"moderation_reason": input.Reason,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Anything still up for sale comes down with the seller
+		var listings []models.Listing
+		if err := tx.Where("seller_id = ? AND status IN ?", user.ID,
+			[]models.ListingStatus{models.StatusActive, models.StatusReserved, models.StatusPending}).
+			Find(&listings).Error; err != nil {
+			return err
+		}
+		for i := range listings {
+			if err := tx.Model(&listings[i]).Update("moderation_reason", "Seller account banned").Error; err != nil {
+				return err
+			}
+			if err := setListingStatus(tx, &listings[i], models.StatusRemoved, models.ActorAdmin,
+				c.GetUint("userID"), "Seller account banned"); err != nil {
+				return err
+			}
+		}
+		removed = int64(len(listings))
+
+		return recordAudit(tx, c, models.AuditUserBan, "user", user.ID, input.Reason,
+			gin.H{"listings_removed": removed})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error banning user"})
+		return
+	}
+
+	revokeUserSessions(user.ID, 0)
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Your UF Market account has been banned",
+		Body: "Hi " + user.FirstName + ",\n\n" +
+			"Your UF Market account has been permanently banned and your listings were taken down.\n\n" +
+			"Reason: " + input.Reason + "\n",
+	})
+
+	c.JSON(http.StatusOK, toAdminUser(user))
+}
+
+// ReinstateUser lifts a suspension or ban. Listings taken down by a ban
+// stay down until an admin restores them.
+func ReinstateUser(c *gin.Context) {
+	user, ok := loadUserParam(c)
+	if !ok {
+		return
+	}
+
+	var input ModerationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	if !user.IsSuspended() && !user.IsBanned() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This account is not suspended or banned"})
+		return
+	}
+
+	// Only those who can ban can lift a ban
+	if user.IsBanned() && !middleware.HasPermission(c, models.PermUsersBan) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to do this", "permission": models.PermUsersBan})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"suspended_until":   nil,
+			"banned_at":         nil,
+			"moderation_reason": "",
+		}).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditUserReinstate, "user", user.ID, input.Reason, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reinstating user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toAdminUser(user))
+}
+
+// SetUserRole makes a user a regular user, moderator or admin. Roles are
+// looked up on every request, so the change applies immediately.
+func SetUserRole(c *gin.Context) {
+	user, ok := loadUserParam(c)
+	if !ok {
+		return
+	}
+
+	var input SetRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil || !input.Role.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be user, moderator or admin"})
+		return
+	}
+
+	if user.ID == c.GetUint("userID") && input.Role != models.RoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot remove your own admin access"})
+		return
+	}
+	if user.Role == input.Role {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This user already has that role"})
+		return
+	}
+
+	previous := user.Role
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("role", input.Role).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditUserSetRole, "user", user.ID, strings.TrimSpace(input.Reason),
+			gin.H{"from": previous, "to": input.Role})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toAdminUser(user))
+}
+
+// loadUserParam resolves the :id route param to a user, writing the error
+// response itself when the user can't be found.
+func loadUserParam(c *gin.Context) (models.User, bool) {
+	var user models.User
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return user, false
+	}
+
+	if result := database.DB.First(&user, id); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+
+	return user, true
+}
+
+// loadModeratedUser is loadUserParam for suspend and ban, which can't target
+// the acting user or other staff.
+func loadModeratedUser(c *gin.Context) (models.User, bool) {
+	user, ok := loadUserParam(c)
+	if !ok {
+		return user, false
+	}
+
+	if user.ID == c.GetUint("userID") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot moderate your own account"})
+		return user, false
+	}
+	if user.Role != models.RoleUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Change this user's role to user before moderating the account"})
+		return user, false
+	}
+	if user.IsBanned() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This account is already banned"})
+		return user, false
+	}
+
+	return user, true
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"strings"
 	"uf-marketplace/database"
@@ -23,8 +24,10 @@ type LoginInput struct {
 }
 
 type AuthResponse struct {
-	Token string              `json:"token"`
-	User  models.UserResponse `json:"user"`
+	Token        string              `json:"token"`
+	RefreshToken string              `json:"refresh_token"`
+	ExpiresIn    int                 `json:"expires_in"`
+	User         models.UserResponse `json:"user"`
 }
 
 func Register(c *gin.Context) {
@@ -81,17 +84,13 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Generate token
-	token, err := utils.GenerateToken(user.ID, user.Email, user.IsAdmin)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
-		return
+	// Prove the address belongs to them before they can list or message
+	if err := sendVerificationEmail(&user); err != nil {
+		log.Printf("Failed to start email verification for user %d: %v", user.ID, err)
 	}
 
-	c.JSON(http.StatusCreated, AuthResponse{
-		Token: token,
-		User:  user.ToResponse(),
-	})
+	// Sign the new user in
+	startSession(c, http.StatusCreated, user)
 }
 
 func Login(c *gin.Context) {
@@ -110,27 +109,43 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	email := strings.ToLower(input.Email)
+
 	var user models.User
-	if result := database.DB.Where("email = ?", strings.ToLower(input.Email)).First(&user); result.Error != nil {
+	found := database.DB.Where("email = ?", email).First(&user).Error == nil
+
+	// Throttled attempts are refused before the password is even checked
+	if loginThrottled(c, email, user.ID) {
+		return
+	}
+
+	if !found {
+		recordLoginAttempt(c, email, 0, models.LoginUnknownEmail)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password. Please try again."})
 		return
 	}
 
 	if !utils.CheckPassword(input.Password, user.Password) {
+		recordLoginFailure(c, &user, models.LoginBadPassword)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password. Please try again."})
 		return
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Email, user.IsAdmin)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+	if message := accountRestriction(&user); message != "" {
+		recordLoginAttempt(c, email, user.ID, models.LoginRestricted)
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
 		return
 	}
 
-	c.JSON(http.StatusOK, AuthResponse{
-		Token: token,
-		User:  user.ToResponse(),
-	})
+	if user.TwoFactorEnabled() {
+		startTwoFactor(c, &user)
+		return
+	}
+
+	recordLoginAttempt(c, email, user.ID, models.LoginSuccess)
+	clearLoginFailures(email)
+
+	startSession(c, http.StatusOK, user)
 }
 
 func GetMe(c *gin.Context) {
@@ -144,3 +159,15 @@ func GetMe(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user.ToResponse())
 }
+
+// accountRestriction explains why a suspended or banned user can't sign in,
+// or returns "" when they can.
+func accountRestriction(user *models.User) string {
+	if user.IsBanned() {
+		return "This account has been banned"
+	}
+	if user.IsSuspended() {
+		return "This account is suspended until " + user.SuspendedUntil.Format("January 2, 2006")
+	}
+	return ""
+}
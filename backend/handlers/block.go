@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BlockResponse is one entry in the current user's block list.
+type BlockResponse struct {
+	ID        uint                `json:"id"`
+	User      models.UserResponse `json:"user"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// BlockUser stops a user from messaging the current user or seeing their
+// listings. Blocking someone who is already blocked is a no-op.
+func BlockUser(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	user, ok := loadUserParam(c)
+	if !ok {
+		return
+	}
+
+	if user.ID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot block yourself"})
+		return
+	}
+
+	block := models.UserBlock{BlockerID: userID, BlockedID: user.ID}
+	if result := database.DB.Where(block).FirstOrCreate(&block); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error blocking user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, BlockResponse{ID: block.ID, User: user.ToResponse(), CreatedAt: block.CreatedAt})
+}
+
+func UnblockUser(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	user, ok := loadUserParam(c)
+	if !ok {
+		return
+	}
+
+	database.DB.Where("blocker_id = ? AND blocked_id = ?", userID, user.ID).Delete(&models.UserBlock{})
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unblocked"})
+}
+
+// GetMyBlocks lists the users the current user has blocked, newest first.
+func GetMyBlocks(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var blocks []models.UserBlock
+	if result := database.DB.Preload("Blocked").Where("blocker_id = ?", userID).
+		Order("id DESC").Find(&blocks); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching blocked users"})
+		return
+	}
+
+	response := make([]BlockResponse, len(blocks))
+	for i, block := range blocks {
+		response[i] = BlockResponse{ID: block.ID, User: block.Blocked.ToResponse(), CreatedAt: block.CreatedAt}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// MuteChat stops new-message notifications for the current user's side of
+// a chat; UnmuteChat turns them back on.
+func MuteChat(c *gin.Context) {
+	setChatMuted(c, true)
+}
+
+func UnmuteChat(c *gin.Context) {
+	setChatMuted(c, false)
+}
+
+func setChatMuted(c *gin.Context, muted bool) {
+	userID := c.GetUint("userID")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	var chat models.Chat
+	if result := database.DB.First(&chat, id); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+		return
+	}
+
+	column := "buyer_muted"
+	switch userID {
+	case chat.BuyerID:
+	case chat.SellerID:
+		column = "seller_muted"
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to update this chat"})
+		return
+	}
+
+	if result := database.DB.Model(&chat).Update(column, muted); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating chat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"chat_id": chat.ID, "muted": muted})
+}
+
+// isBlockedEither reports whether either user has blocked the other.
+func isBlockedEither(a, b uint) bool {
+	var count int64
+	database.DB.Model(&models.UserBlock{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", a, b, b, a).
+		Count(&count)
+	return count > 0
+}
+
+// isBlockedBy reports whether blockerID has blocked userID.
+func isBlockedBy(blockerID, userID uint) bool {
+	if userID == 0 {
+		return false
+	}
+	var count int64
+	database.DB.Model(&models.UserBlock{}).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, userID).
+		Count(&count)
+	return count > 0
+}
+
+// hideBlockersListings leaves out listings by sellers who blocked viewerID.
+func hideBlockersListings(query *gorm.DB, viewerID uint) *gorm.DB {
+	if viewerID == 0 {
+		return query
+	}
+	return query.Where("listings.seller_id NOT IN (?)",
+		database.DB.Model(&models.UserBlock{}).Select("blocker_id").Where("blocked_id = ?", viewerID))
+}
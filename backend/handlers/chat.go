@@ -6,10 +6,18 @@ import (
 	"time"
 	"uf-marketplace/database"
 	"uf-marketplace/models"
+	"uf-marketplace/notify"
+	"uf-marketplace/realtime"
+	"uf-marketplace/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	defaultMessageLimit = 50
+	maxMessageLimit     = 200
+)
+
 type CreateChatInput struct {
 	ListingID uint   `json:"listing_id" binding:"required"`
 	Message   string `json:"message" binding:"required"`
@@ -57,6 +65,8 @@ func GetChats(c *gin.Context) {
 			SellerID:    chat.SellerID,
 			Seller:      chat.Seller.ToResponse(),
 			UnreadCount: int(unreadCount),
+			Muted:       chat.IsMutedBy(userID),
+			Blocked:     isBlockedEither(chat.BuyerID, chat.SellerID),
 			CreatedAt:   chat.CreatedAt,
 			UpdatedAt:   chat.UpdatedAt,
 		}
@@ -93,6 +103,11 @@ func CreateChat(c *gin.Context) {
 		return
 	}
 
+	if isBlockedEither(userID, listing.SellerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can't message this user"})
+		return
+	}
+
 	// Check if chat already exists
 	var existingChat models.Chat
 	result := database.DB.
@@ -109,6 +124,9 @@ func CreateChat(c *gin.Context) {
 		database.DB.Create(&message)
 		database.DB.Model(&existingChat).Update("updated_at", time.Now())
 
+		database.DB.Preload("Sender").First(&message, message.ID)
+		publishChatEvent(existingChat, realtime.EventNewMessage, message)
+
 		c.JSON(http.StatusOK, gin.H{
 			"chat_id": existingChat.ID,
 			"message": message,
@@ -137,14 +155,12 @@ func CreateChat(c *gin.Context) {
 	database.DB.Create(&message)
 
 	// Create notification for seller
-	notification := models.Notification{
-		UserID:  listing.SellerID,
-		Type:    models.NotificationNewMessage,
-		Title:   "New Message",
-		Message: "You have a new message about your listing: " + listing.Title,
-		Link:    "/chat/" + strconv.Itoa(int(chat.ID)),
-	}
-	database.DB.Create(&notification)
+	notify.Send(listing.SellerID, models.NotificationNewMessage, "New Message",
+		"You have a new message about your listing: "+listing.Title,
+		"/chat/"+strconv.Itoa(int(chat.ID)))
+
+	database.DB.Preload("Sender").First(&message, message.ID)
+	publishChatEvent(chat, realtime.EventNewMessage, message)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"chat_id": chat.ID,
@@ -204,20 +220,53 @@ func GetChatMessages(c *gin.Context) {
 		return
 	}
 
+	var errs queryErrors
+	limit := errs.queryInt(c, "limit", defaultMessageLimit, 1, maxMessageLimit)
+	cursor, hasCursor := errs.queryCursor(c)
+	before, hasBefore := errs.queryID(c, "before")
+	after, hasAfter := errs.queryID(c, "after")
+	if hasBefore && hasAfter {
+		errs.add("after", "cannot be combined with before")
+	}
+	if errs.respond(c) {
+		return
+	}
+
+	// Without a position, start from the newest page and scroll back
+	if !hasCursor {
+		switch {
+		case hasBefore:
+			cursor = utils.Cursor{ID: before, Before: true}
+		case hasAfter:
+			cursor = utils.Cursor{ID: after}
+		default:
+			cursor = utils.Cursor{Before: true}
+		}
+	}
+
+	// Oldest first, the order the chat widget renders them in
+	order := keyset{IDColumn: "id"}
+
 	var messages []models.Message
-	database.DB.
-		Preload("Sender").
-		Where("chat_id = ?", id).
-		Order("created_at ASC").
+	result := order.apply(database.DB.Preload("Sender").Where("chat_id = ?", id), cursor, nil).
+		Limit(limit + 1).
 		Find(&messages)
 
-	// Mark messages as read
-	now := time.Now()
-	database.DB.Model(&models.Message{}).
-		Where("chat_id = ? AND sender_id != ? AND is_read = ?", id, userID, false).
-		Updates(map[string]interface{}{"is_read": true, "read_at": now})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching messages"})
+		return
+	}
+
+	messages, hasNewer, hasOlder := trimPage(messages, limit, cursor)
+
+	markChatRead(chat, userID)
+
+	response := gin.H{"messages": messages}
+	if len(messages) > 0 {
+		setIDCursors(response, messages[0].ID, messages[len(messages)-1].ID, hasNewer, hasOlder)
+	}
 
-	c.JSON(http.StatusOK, messages)
+	c.JSON(http.StatusOK, response)
 }
 
 func SendMessage(c *gin.Context) {
@@ -247,6 +296,19 @@ func SendMessage(c *gin.Context) {
 		return
 	}
 
+	// Create notification for recipient
+	var recipientID uint
+	if chat.BuyerID == userID {
+		recipientID = chat.SellerID
+	} else {
+		recipientID = chat.BuyerID
+	}
+
+	if isBlockedEither(userID, recipientID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can't message this user"})
+		return
+	}
+
 	message := models.Message{
 		ChatID:   uint(id),
 		SenderID: userID,
@@ -261,25 +323,30 @@ func SendMessage(c *gin.Context) {
 	// Update chat timestamp
 	database.DB.Model(&chat).Update("updated_at", time.Now())
 
-	// Create notification for recipient
-	var recipientID uint
-	if chat.BuyerID == userID {
-		recipientID = chat.SellerID
-	} else {
-		recipientID = chat.BuyerID
-	}
-
-	notification := models.Notification{
-		UserID:  recipientID,
-		Type:    models.NotificationNewMessage,
-		Title:   "New Message",
-		Message: "You have a new message about: " + chat.Listing.Title,
-		Link:    "/chat/" + strconv.Itoa(int(chat.ID)),
+	// Notify the recipient unless they muted the chat
+	if !chat.IsMutedBy(recipientID) {
+		notify.Send(recipientID, models.NotificationNewMessage, "New Message",
+			"You have a new message about: "+chat.Listing.Title,
+			"/chat/"+strconv.Itoa(int(chat.ID)))
 	}
-	database.DB.Create(&notification)
 
 	// Reload with sender
 	database.DB.Preload("Sender").First(&message, message.ID)
 
+	publishChatEvent(chat, realtime.EventNewMessage, message)
+
 	c.JSON(http.StatusCreated, message)
 }
+
+// markChatRead marks the other participant's messages as read and sends a
+// read receipt to both sides of the chat.
+func markChatRead(chat models.Chat, userID uint) {
+	now := time.Now()
+	result := database.DB.Model(&models.Message{}).
+		Where("chat_id = ? AND sender_id != ? AND is_read = ?", chat.ID, userID, false).
+		Updates(map[string]interface{}{"is_read": true, "read_at": now})
+
+	if result.Error == nil && result.RowsAffected > 0 {
+		publishChatEvent(chat, realtime.EventRead, gin.H{"reader_id": userID, "read_at": now})
+	}
+}
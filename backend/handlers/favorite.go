@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+func FavoriteListing(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	listing, ok := loadListingParam(c)
+	if !ok {
+		return
+	}
+
+	if listing.SellerID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot watch your own listing"})
+		return
+	}
+
+	favorite := models.Favorite{UserID: userID, ListingID: listing.ID}
+	if result := database.DB.Where(favorite).FirstOrCreate(&favorite); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving favorite"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Listing added to favorites", "favorite_id": favorite.ID})
+}
+
+func UnfavoriteListing(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	listing, ok := loadListingParam(c)
+	if !ok {
+		return
+	}
+
+	database.DB.Where("user_id = ? AND listing_id = ?", userID, listing.ID).Delete(&models.Favorite{})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Listing removed from favorites"})
+}
+
+func GetMyFavorites(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var favorites []models.Favorite
+	result := database.DB.
+		Preload("Listing").
+		Preload("Listing.Images").
+		Preload("Listing.Category").
+		Preload("Listing.Seller").
+		Joins("JOIN listings ON listings.id = favorites.listing_id AND listings.deleted_at IS NULL").
+		Where("favorites.user_id = ?", userID).
+		Order("favorites.created_at DESC").
+		Find(&favorites)
+
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching favorites"})
+		return
+	}
+
+	c.JSON(http.StatusOK, favorites)
+}
+
+// notifyPriceDrop tells everyone watching a listing that its price went down.
+func notifyPriceDrop(listing models.Listing, oldPrice float64) {
+	var watcherIDs []uint
+	database.DB.Model(&models.Favorite{}).
+		Where("listing_id = ? AND user_id != ?", listing.ID, listing.SellerID).
+		Pluck("user_id", &watcherIDs)
+
+	message := fmt.Sprintf("%s dropped from $%.2f to $%.2f", listing.Title, oldPrice, listing.Price)
+	for _, watcherID := range watcherIDs {
+		notify.Send(watcherID, models.NotificationPriceDropped, "Price Dropped", message,
+			notify.ListingLink(listing.ID))
+	}
+}
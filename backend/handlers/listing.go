@@ -2,14 +2,19 @@ package handlers
 
 import (
 	"fmt"
+	"math"
 	"net/http"
-	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 	"uf-marketplace/database"
+	"uf-marketplace/middleware"
 	"uf-marketplace/models"
+	"uf-marketplace/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type CreateListingInput struct {
@@ -42,6 +47,10 @@ func CreateListing(c *gin.Context) {
 		return
 	}
 
+	if !checkListingImages(c, userID, 0, input.Images) {
+		return
+	}
+
 	// Verify category exists
 	var category models.Category
 	if result := database.DB.First(&category, input.CategoryID); result.Error != nil {
@@ -59,87 +68,232 @@ func CreateListing(c *gin.Context) {
 		Location:    input.Location,
 		Status:      models.StatusActive,
 	}
+	expiresAt := time.Now().Add(category.ListingLifetime())
+	listing.ExpiresAt = &expiresAt
 
-	if result := database.DB.Create(&listing); result.Error != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&listing).Error; err != nil {
+			return err
+		}
+		return recordStatusChange(tx, listing.ID, "", listing.Status, models.ActorSeller, userID, "")
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating listing"})
 		return
 	}
 
 	// Add images
 	for i, imageURL := range input.Images {
-		image := models.ListingImage{
-			ListingID: listing.ID,
-			ImageURL:  imageURL,
-			IsPrimary: i == 0,
-		}
+		image := newListingImage(listing.ID, imageURL, i == 0)
 		database.DB.Create(&image)
 	}
 
 	// Reload with associations
 	database.DB.Preload("Images").Preload("Category").Preload("Seller").First(&listing, listing.ID)
 
+	// Let users whose saved searches match know it's up
+	matchSavedSearches(listing)
+
 	c.JSON(http.StatusCreated, listing)
 }
 
+// listingSortColumns whitelists the fields GetListings may sort by.
+// "relevance" is handled separately since it only applies to searches.
+var listingSortColumns = map[string]string{
+	"price":      "listings.price",
+	"created_at": "listings.created_at",
+	"views":      "listings.views",
+	"title":      "listings.title",
+}
+
+const (
+	defaultListingLimit = 20
+	maxListingLimit     = 100
+)
+
 func GetListings(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	search := c.Query("search")
-	categoryID := c.Query("category_id")
-	minPrice := c.Query("min_price")
-	maxPrice := c.Query("max_price")
+	var errs queryErrors
+
+	page := errs.queryInt(c, "page", 1, 1, math.MaxInt32)
+	limit := errs.queryInt(c, "limit", defaultListingLimit, 1, maxListingLimit)
+	search := strings.TrimSpace(c.Query("search"))
+	categoryID, hasCategory := errs.queryID(c, "category_id")
+	minPrice, hasMinPrice := errs.queryPrice(c, "min_price")
+	maxPrice, hasMaxPrice := errs.queryPrice(c, "max_price")
+	if hasMinPrice && hasMaxPrice && minPrice > maxPrice {
+		errs.add("max_price", "must be greater than or equal to min_price")
+	}
+
 	condition := c.Query("condition")
+	if condition != "" && !slices.Contains(models.ListingConditions, condition) {
+		errs.add("condition", "must be one of "+strings.Join(models.ListingConditions, ", "))
+	}
+
 	sortBy := c.DefaultQuery("sort", "created_at")
-	sortOrder := c.DefaultQuery("order", "desc")
+	sortColumn, sortable := listingSortColumns[sortBy]
+	if !sortable && sortBy != "relevance" {
+		errs.add("sort", "must be one of price, created_at, views, title, relevance")
+	}
+
+	sortOrder := strings.ToLower(c.DefaultQuery("order", "desc"))
+	if sortOrder != "asc" && sortOrder != "desc" {
+		errs.add("order", "must be asc or desc")
+	}
+
+	cursor, hasCursor := errs.queryCursor(c)
+
+	if errs.respond(c) {
+		return
+	}
 
 	offset := (page - 1) * limit
 
-	query := database.DB.Model(&models.Listing{}).Where("status = ?", models.StatusActive)
+	query := database.DB.Model(&models.Listing{}).
+		Where("listings.status = ? AND listings.hidden_at IS NULL", models.StatusActive).
+		Where("listings.expires_at IS NULL OR listings.expires_at > ?", time.Now())
+	query = hideBlockersListings(query, c.GetUint("userID"))
 
 	// Apply filters
+	fullText := false
 	if search != "" {
-		query = query.Where("title LIKE ? OR description LIKE ?", "%"+search+"%", "%"+search+"%")
+		if database.FTSEnabled {
+			match := utils.FTSQuery(search)
+			if match == "" {
+				queryErrors{{Field: "search", Message: "must contain at least one letter or number"}}.respond(c)
+				return
+			}
+			query = query.
+				Joins("JOIN listings_fts ON listings_fts.rowid = listings.id").
+				Where("listings_fts MATCH ?", match)
+			fullText = true
+		} else {
+			query = query.Where("(listings.title LIKE ? OR listings.description LIKE ?)", "%"+search+"%", "%"+search+"%")
+		}
+	}
+	// Relevance only means something for full-text searches
+	if sortBy == "relevance" && !fullText {
+		sortBy, sortColumn, sortOrder = "created_at", listingSortColumns["created_at"], "desc"
+	}
+
+	// A cursor is only valid for the ordering it was issued under
+	sortKey := sortBy + ":" + sortOrder
+	if hasCursor && cursor.Sort != sortKey {
+		queryErrors{{Field: "cursor", Message: "does not match the requested sort and order"}}.respond(c)
+		return
 	}
-	if categoryID != "" {
-		query = query.Where("category_id = ?", categoryID)
+
+	if hasCategory {
+		query = query.Where("listings.category_id = ?", categoryID)
 	}
-	if minPrice != "" {
-		query = query.Where("price >= ?", minPrice)
+	if hasMinPrice {
+		query = query.Where("listings.price >= ?", minPrice)
 	}
-	if maxPrice != "" {
-		query = query.Where("price <= ?", maxPrice)
+	if hasMaxPrice {
+		query = query.Where("listings.price <= ?", maxPrice)
 	}
 	if condition != "" {
-		query = query.Where("condition = ?", condition)
+		query = query.Where("listings.condition = ?", condition)
 	}
 
 	// Count total
 	var total int64
 	query.Count(&total)
 
-	// Apply sorting and pagination
-	var listings []models.Listing
-	result := query.
+	if fullText {
+		query = query.Select(`listings.*,
+			highlight(listings_fts, 0, ?, ?) AS title_highlight,
+			snippet(listings_fts, 1, ?, ?, '…', 16) AS description_snippet`,
+			utils.FTSMarkStart, utils.FTSMarkEnd, utils.FTSMarkStart, utils.FTSMarkEnd)
+	}
+
+	query = query.
 		Preload("Images").
 		Preload("Category").
-		Preload("Seller").
-		Order(fmt.Sprintf("%s %s", sortBy, sortOrder)).
-		Offset(offset).
-		Limit(limit).
-		Find(&listings)
+		Preload("Seller")
+
+	response := gin.H{"total": total, "limit": limit}
+
+	var listings []models.Listing
+	var result *gorm.DB
+
+	if sortBy == "relevance" {
+		// bm25 scores can't be keyset, so relevance cursors carry an offset.
+		// Rank by bm25, weighting title matches above description matches.
+		if hasCursor {
+			offset = cursor.Offset
+		}
+		result = query.Order("bm25(listings_fts, 10.0, 1.0)").Order("listings.id").
+			Offset(offset).Limit(limit).Find(&listings)
+
+		if int64(offset+limit) < total {
+			response["next_cursor"] = utils.EncodeCursor(utils.Cursor{Offset: offset + limit, Sort: sortKey})
+		}
+		if offset > 0 {
+			response["prev_cursor"] = utils.EncodeCursor(utils.Cursor{Offset: max(offset-limit, 0), Sort: sortKey})
+		}
+	} else {
+		order := keyset{Column: sortColumn, IDColumn: "listings.id", Desc: sortOrder == "desc"}
+
+		hasNext, hasPrev := false, false
+		if hasCursor {
+			// Keyset pagination: fetch one extra row to learn if there's more
+			value := cursor.Value
+			if sortBy == "created_at" {
+				value, _ = time.Parse(time.RFC3339Nano, fmt.Sprint(cursor.Value))
+			}
+			result = order.apply(query, cursor, value).Limit(limit + 1).Find(&listings)
+			listings, hasNext, hasPrev = trimPage(listings, limit, cursor)
+		} else {
+			result = order.apply(query, utils.Cursor{}, nil).Offset(offset).Limit(limit).Find(&listings)
+			hasNext = int64(offset+len(listings)) < total
+			hasPrev = page > 1
+			response["page"] = page
+			response["pages"] = (total + int64(limit) - 1) / int64(limit)
+		}
+
+		if len(listings) > 0 {
+			first, last := listings[0], listings[len(listings)-1]
+			if hasNext {
+				response["next_cursor"] = utils.EncodeCursor(utils.Cursor{
+					ID: last.ID, Value: listingSortValue(last, sortBy), Sort: sortKey,
+				})
+			}
+			if hasPrev {
+				response["prev_cursor"] = utils.EncodeCursor(utils.Cursor{
+					ID: first.ID, Value: listingSortValue(first, sortBy), Sort: sortKey, Before: true,
+				})
+			}
+		}
+	}
 
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching listings"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"listings": listings,
-		"total":    total,
-		"page":     page,
-		"limit":    limit,
-		"pages":    (total + int64(limit) - 1) / int64(limit),
-	})
+	// Titles and descriptions are user text, so escape them around the marks
+	for i := range listings {
+		listings[i].TitleHighlight = utils.HighlightHTML(listings[i].TitleHighlight)
+		listings[i].DescriptionSnippet = utils.HighlightHTML(listings[i].DescriptionSnippet)
+	}
+
+	response["listings"] = listings
+	c.JSON(http.StatusOK, response)
+}
+
+// listingSortValue returns the value of the column a listing is sorted by,
+// for embedding in a cursor.
+func listingSortValue(listing models.Listing, sortBy string) interface{} {
+	switch sortBy {
+	case "price":
+		return listing.Price
+	case "views":
+		return listing.Views
+	case "title":
+		return listing.Title
+	default:
+		return listing.CreatedAt.Format(time.RFC3339Nano)
+	}
 }
 
 func GetListing(c *gin.Context) {
@@ -162,6 +316,19 @@ func GetListing(c *gin.Context) {
 		return
 	}
 
+	// Removed and hidden listings are only visible to their seller and moderators
+	moderated := listing.Status == models.StatusRemoved || listing.HiddenAt != nil
+	if moderated && listing.SellerID != c.GetUint("userID") && !middleware.HasPermission(c, models.PermListingsModerate) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return
+	}
+
+	// Sellers who blocked the viewer don't exist as far as they can tell
+	if isBlockedBy(listing.SellerID, c.GetUint("userID")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return
+	}
+
 	// Increment view count
 	database.DB.Model(&listing).Update("views", listing.Views+1)
 
@@ -189,12 +356,29 @@ func UpdateListing(c *gin.Context) {
 		return
 	}
 
+	if listing.Status == models.StatusRemoved {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This listing was removed by a moderator and can't be edited"})
+		return
+	}
+
+	// The sale is on record, so the listing stays as it was sold
+	if listing.Status == models.StatusSold {
+		c.JSON(http.StatusConflict, gin.H{"error": "Sold listings can't be edited"})
+		return
+	}
+
 	var input UpdateListingInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if !checkListingImages(c, userID, listing.ID, input.Images) {
+		return
+	}
+
+	oldPrice := listing.Price
+
 	// Update fields
 	if input.Title != "" {
 		listing.Title = input.Title
@@ -214,25 +398,42 @@ func UpdateListing(c *gin.Context) {
 	if input.Location != "" {
 		listing.Location = input.Location
 	}
-	if input.Status != "" {
-		listing.Status = models.ListingStatus(input.Status)
+
+	// Status changes go through the state machine like PUT /:id/status
+	status := models.ListingStatus(input.Status)
+	changeStatus := status != "" && status != listing.Status
+	if changeStatus && !checkStatusChange(c, listing, status, models.ActorSeller) {
+		return
 	}
 
-	if result := database.DB.Save(&listing); result.Error != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&listing).Error; err != nil {
+			return err
+		}
+		if !changeStatus {
+			return nil
+		}
+		return setListingStatus(tx, &listing, status, models.ActorSeller, userID, "")
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating listing"})
 		return
 	}
 
+	if listing.Price < oldPrice {
+		notifyPriceDrop(listing, oldPrice)
+	}
+
 	// Update images if provided
 	if len(input.Images) > 0 {
-		database.DB.Where("listing_id = ?", listing.ID).Delete(&models.ListingImage{})
+		// Built before the old rows go so kept images carry their variants over
+		images := make([]models.ListingImage, len(input.Images))
 		for i, imageURL := range input.Images {
-			image := models.ListingImage{
-				ListingID: listing.ID,
-				ImageURL:  imageURL,
-				IsPrimary: i == 0,
-			}
-			database.DB.Create(&image)
+			images[i] = newListingImage(listing.ID, imageURL, i == 0)
+		}
+		database.DB.Where("listing_id = ?", listing.ID).Delete(&models.ListingImage{})
+		for i := range images {
+			database.DB.Create(&images[i])
 		}
 	}
 
@@ -243,7 +444,7 @@ func UpdateListing(c *gin.Context) {
 
 func DeleteListing(c *gin.Context) {
 	userID := c.GetUint("userID")
-	isAdmin := c.GetBool("isAdmin")
+	canDelete := middleware.HasPermission(c, models.PermListingsDelete)
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
@@ -258,7 +459,7 @@ func DeleteListing(c *gin.Context) {
 	}
 
 	// Check ownership or admin
-	if listing.SellerID != userID && !isAdmin {
+	if listing.SellerID != userID && !canDelete {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to delete this listing"})
 		return
 	}
@@ -272,30 +473,12 @@ func DeleteListing(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Listing deleted successfully"})
-}
-
-func UploadImage(c *gin.Context) {
-	file, err := c.FormFile("image")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No image provided"})
-		return
-	}
-
-	// Generate unique filename
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
-	path := filepath.Join("uploads", filename)
-
-	if err := c.SaveUploadedFile(file, path); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving image"})
-		return
+	if listing.SellerID != userID {
+		recordAudit(database.DB, c, models.AuditListingDelete, "listing", listing.ID, "",
+			gin.H{"title": listing.Title, "seller_id": listing.SellerID})
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"url":      "/uploads/" + filename,
-		"filename": filename,
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "Listing deleted successfully"})
 }
 
 func GetCategories(c *gin.Context) {
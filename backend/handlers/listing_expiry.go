@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RenewListing pushes a listing's expiry back by its category's listing
+// lifetime. Listings that already expired are made active again.
+func RenewListing(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	listing, ok := loadListingParam(c)
+	if !ok {
+		return
+	}
+
+	if listing.SellerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to renew this listing"})
+		return
+	}
+
+	switch listing.Status {
+	case models.StatusActive, models.StatusReserved, models.StatusInactive:
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": "This listing is " + string(listing.Status) + " and can't be renewed"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if listing.Status == models.StatusInactive {
+			if err := setListingStatus(tx, &listing, models.StatusActive, models.ActorSeller, userID, "Renewed"); err != nil {
+				return err
+			}
+		}
+		return renewListing(tx, &listing)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error renewing listing"})
+		return
+	}
+
+	database.DB.Preload("Images").Preload("Category").Preload("Seller").First(&listing, listing.ID)
+
+	c.JSON(http.StatusOK, listing)
+}
+
+// renewListing sets listing to expire one category lifetime from now and
+// re-arms the expiry reminder.
+func renewListing(tx *gorm.DB, listing *models.Listing) error {
+	var category models.Category
+	if err := tx.First(&category, listing.CategoryID).Error; err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(category.ListingLifetime())
+	if err := tx.Model(listing).Updates(map[string]interface{}{
+		"expires_at":         expiresAt,
+		"expiry_reminded_at": nil,
+	}).Error; err != nil {
+		return err
+	}
+	listing.ExpiresAt = &expiresAt
+	listing.ExpiryRemindedAt = nil
+	return nil
+}
+
+// isExpired reports whether listing is past its expiry date.
+func isExpired(listing models.Listing) bool {
+	return listing.ExpiresAt != nil && !listing.ExpiresAt.After(time.Now())
+}
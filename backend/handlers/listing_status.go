@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"uf-marketplace/database"
+	"uf-marketplace/middleware"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ListingStatusInput struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// UpdateListingStatus moves a listing through its status state machine.
+// Sellers manage their own listings; moderators and admins can move
+// anyone's and must give a reason. Selling and removing have their own
+// endpoints since they need more than a status.
+func UpdateListingStatus(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	listing, ok := loadListingParam(c)
+	if !ok {
+		return
+	}
+
+	var input ListingStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status is required"})
+		return
+	}
+
+	actor := models.ActorSeller
+	if listing.SellerID != userID {
+		if !middleware.HasPermission(c, models.PermListingsModerate) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to update this listing"})
+			return
+		}
+		actor = models.ActorAdmin
+	}
+
+	to := models.ListingStatus(input.Status)
+	reason := strings.TrimSpace(input.Reason)
+	if !checkStatusChange(c, listing, to, actor) {
+		return
+	}
+	if actor == models.ActorAdmin && reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	from := listing.Status
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := setListingStatus(tx, &listing, to, actor, userID, reason); err != nil {
+			return err
+		}
+		// Bringing a removed listing back clears the moderator's note
+		if from == models.StatusRemoved {
+			if err := tx.Model(&listing).Update("moderation_reason", "").Error; err != nil {
+				return err
+			}
+		}
+		if actor != models.ActorAdmin {
+			return nil
+		}
+		return recordAudit(tx, c, models.AuditListingStatus, "listing", listing.ID, reason,
+			gin.H{"title": listing.Title, "seller_id": listing.SellerID, "from": from, "to": to})
+	})
+	if errors.Is(err, errListingStatusChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This listing was changed by someone else. Reload it and try again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating listing status"})
+		return
+	}
+
+	if actor == models.ActorAdmin {
+		notify.Send(listing.SellerID, models.NotificationListingStatus, "Listing Status Changed",
+			"A moderator changed your listing \""+listing.Title+"\" to "+string(to)+": "+reason,
+			notify.ListingLink(listing.ID))
+	}
+
+	database.DB.Preload("Images").Preload("Category").Preload("Seller").First(&listing, listing.ID)
+
+	c.JSON(http.StatusOK, listing)
+}
+
+// GetListingStatusHistory lists a listing's status changes, oldest first.
+// Only the seller and moderators can see it.
+func GetListingStatusHistory(c *gin.Context) {
+	listing, ok := loadListingParam(c)
+	if !ok {
+		return
+	}
+
+	if listing.SellerID != c.GetUint("userID") && !middleware.HasPermission(c, models.PermListingsModerate) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to view this listing's history"})
+		return
+	}
+
+	var history []models.ListingStatusChange
+	if result := database.DB.Preload("ChangedBy").Where("listing_id = ?", listing.ID).
+		Order("id ASC").Find(&history); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching status history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// checkStatusChange responds with an error and returns false unless actor
+// may move listing to status to through the generic status endpoints.
+func checkStatusChange(c *gin.Context, listing models.Listing, to models.ListingStatus, actor models.StatusActor) bool {
+	switch {
+	case !to.IsValid():
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return false
+	case to == models.StatusSold:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use POST /api/listings/:id/sold to record who bought the listing"})
+		return false
+	case to == models.StatusRemoved:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use POST /api/admin/listings/:id/remove to take a listing down"})
+		return false
+	case to == listing.Status:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The listing is already " + string(to)})
+		return false
+	case !models.CanTransition(listing.Status, to, actor):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "This listing is " + string(listing.Status) + " and can't be changed to " + string(to),
+			"allowed": listing.Status.NextStatuses(actor),
+		})
+		return false
+	case listing.Status == models.StatusRemoved && wasSold(listing):
+		// Its sale still stands, so it can't go back up for sale
+		c.JSON(http.StatusConflict, gin.H{"error": "This listing was sold before it was removed and can't be restored"})
+		return false
+	}
+	return true
+}
+
+// wasSold reports whether a sale has been recorded for the listing.
+func wasSold(listing models.Listing) bool {
+	var count int64
+	database.DB.Model(&models.Transaction{}).Where("listing_id = ?", listing.ID).Count(&count)
+	return count > 0
+}
+
+// errListingStatusChanged means another request changed the listing's
+// status after it was loaded.
+var errListingStatusChanged = errors.New("listing status changed")
+
+// setListingStatus moves listing to status to inside tx and adds the change
+// to its history. Callers check CanTransition first; if the status in the
+// database no longer matches listing it returns errListingStatusChanged.
+// changedByID is 0 for changes made by background jobs.
+func setListingStatus(tx *gorm.DB, listing *models.Listing, to models.ListingStatus, actor models.StatusActor, changedByID uint, reason string) error {
+	from := listing.Status
+	result := tx.Model(listing).Where("status = ?", from).Update("status", to)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errListingStatusChanged
+	}
+	listing.Status = to
+
+	// A listing coming back after it expired gets a fresh lifetime
+	if to == models.StatusActive && (listing.ExpiresAt == nil || isExpired(*listing)) {
+		if err := renewListing(tx, listing); err != nil {
+			return err
+		}
+	}
+
+	return recordStatusChange(tx, listing.ID, from, to, actor, changedByID, reason)
+}
+
+func recordStatusChange(tx *gorm.DB, listingID uint, from, to models.ListingStatus, actor models.StatusActor, changedByID uint, reason string) error {
+	change := models.ListingStatusChange{
+		ListingID:  listingID,
+		FromStatus: from,
+		ToStatus:   to,
+		Actor:      actor,
+		Reason:     reason,
+	}
+	if changedByID != 0 {
+		change.ChangedByID = &changedByID
+	}
+	return tx.Create(&change).Error
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/mailer"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// loginLimit throttles failed sign-ins sharing a column value. After Free
+// failures within Window each further attempt has to wait twice as long as
+// the last, starting at loginBackoffBase; at Lockout failures the wait
+// becomes loginLockoutDuration.
+type loginLimit struct {
+	Column  string
+	Window  time.Duration
+	Free    int64
+	Lockout int64
+}
+
+const (
+	loginBackoffBase     = 2 * time.Second
+	loginLockoutDuration = 15 * time.Minute
+)
+
+var (
+	accountLoginLimit = loginLimit{Column: "email", Window: 24 * time.Hour, Free: 3, Lockout: 8}
+	ipLoginLimit      = loginLimit{Column: "ip_address", Window: time.Hour, Free: 10, Lockout: 30}
+)
+
+type UnlockInput struct {
+	Reason string `json:"reason"`
+}
+
+// wait returns how many failures count against value and when it may try
+// to sign in again; the time is zero when it isn't throttled.
+func (l loginLimit) wait(value string) (int64, time.Time) {
+	failures := func() *gorm.DB {
+		return database.DB.Model(&models.LoginAttempt{}).
+			Where(l.Column+" = ? AND outcome IN ? AND cleared_at IS NULL AND created_at > ?",
+				value, models.LoginFailures, time.Now().Add(-l.Window))
+	}
+
+	var count int64
+	failures().Count(&count)
+
+	delay := l.backoff(count)
+	if delay == 0 {
+		return count, time.Time{}
+	}
+
+	var last models.LoginAttempt
+	if result := failures().Order("created_at DESC").First(&last); result.Error != nil {
+		return count, time.Time{}
+	}
+	return count, last.CreatedAt.Add(delay)
+}
+
+func (l loginLimit) backoff(failures int64) time.Duration {
+	if failures >= l.Lockout {
+		return loginLockoutDuration
+	}
+	if failures < l.Free {
+		return 0
+	}
+	delay := loginBackoffBase << (failures - l.Free)
+	if delay > loginLockoutDuration {
+		return loginLockoutDuration
+	}
+	return delay
+}
+
+// loginThrottled responds with 429 if the email or IP has to wait before
+// trying again. userID is 0 for unknown emails.
+func loginThrottled(c *gin.Context, email string, userID uint) bool {
+	_, accountUntil := accountLoginLimit.wait(email)
+	_, ipUntil := ipLoginLimit.wait(c.ClientIP())
+	until := accountUntil
+	if ipUntil.After(until) {
+		until = ipUntil
+	}
+
+	retryAfter := time.Until(until)
+	if retryAfter <= 0 {
+		return false
+	}
+
+	recordLoginAttempt(c, email, userID, models.LoginLockedOut)
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed sign-in attempts. Please try again in " + describeWait(retryAfter) + ".",
+		"retry_after": seconds,
+	})
+	return true
+}
+
+func recordLoginAttempt(c *gin.Context, email string, userID uint, outcome models.LoginOutcome) {
+	database.DB.Create(&models.LoginAttempt{
+		UserID:    userID,
+		Email:     email,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Outcome:   outcome,
+	})
+}
+
+// recordLoginFailure logs a wrong password or two-factor code and tells the
+// account owner when it is the one that locks the account.
+func recordLoginFailure(c *gin.Context, user *models.User, outcome models.LoginOutcome) {
+	recordLoginAttempt(c, user.Email, user.ID, outcome)
+
+	failures, _ := accountLoginLimit.wait(user.Email)
+	if failures != accountLoginLimit.Lockout {
+		return
+	}
+
+	notify.Send(user.ID, models.NotificationSecurity, "Sign-in Locked",
+		fmt.Sprintf("Your account was locked for %s after %d failed sign-in attempts. If this wasn't you, change your password.",
+			describeWait(loginLockoutDuration), failures),
+		"/settings")
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Your UF Market account was locked",
+		Body: "Hi " + user.FirstName + ",\n\n" +
+			fmt.Sprintf("There were %d failed attempts to sign in to your account, the last one from %s, so sign-in is locked for %s.\n\n",
+				failures, c.ClientIP(), describeWait(loginLockoutDuration)) +
+			"If this wasn't you, reset your password to be safe:\n\n" +
+			appURL("/forgot-password") + "\n",
+	})
+}
+
+// clearLoginFailures stops the account's earlier failures counting towards
+// a lockout and returns how many were cleared.
+func clearLoginFailures(email string) int64 {
+	result := database.DB.Model(&models.LoginAttempt{}).
+		Where("email = ? AND outcome IN ? AND cleared_at IS NULL", email, models.LoginFailures).
+		Update("cleared_at", time.Now())
+	return result.RowsAffected
+}
+
+// describeWait turns a wait into "30 seconds" or "15 minutes".
+func describeWait(d time.Duration) string {
+	if d < time.Minute {
+		seconds := int(math.Ceil(d.Seconds()))
+		if seconds == 1 {
+			return "1 second"
+		}
+		return strconv.Itoa(seconds) + " seconds"
+	}
+	minutes := int(math.Ceil(d.Minutes()))
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return strconv.Itoa(minutes) + " minutes"
+}
+
+// UnlockUser clears a sign-in lockout on the account so the owner can try
+// again right away.
+func UnlockUser(c *gin.Context) {
+	user, ok := loadUserParam(c)
+	if !ok {
+		return
+	}
+
+	var input UnlockInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	cleared := clearLoginFailures(user.Email)
+	if cleared == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This account has no failed sign-ins to clear"})
+		return
+	}
+
+	if err := recordAudit(database.DB, c, models.AuditUserUnlock, "user", user.ID, strings.TrimSpace(input.Reason),
+		gin.H{"cleared": cleared}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error unlocking user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toAdminUser(user))
+}
+
+// GetLoginAttempts lists sign-in attempts newest first, filtered by email,
+// IP, user or outcome.
+func GetLoginAttempts(c *gin.Context) {
+	var errs queryErrors
+	limit := errs.queryInt(c, "limit", defaultAdminPageLimit, 1, maxAdminPageLimit)
+	cursor, _ := errs.queryCursor(c)
+	userID, hasUser := errs.queryID(c, "user_id")
+	if errs.respond(c) {
+		return
+	}
+
+	query := database.DB.Model(&models.LoginAttempt{})
+	if email := c.Query("email"); email != "" {
+		query = query.Where("email = ?", strings.ToLower(strings.TrimSpace(email)))
+	}
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip_address = ?", ip)
+	}
+	if hasUser {
+		query = query.Where("user_id = ?", userID)
+	}
+	if outcome := c.Query("outcome"); outcome != "" {
+		query = query.Where("outcome = ?", outcome)
+	}
+
+	order := keyset{IDColumn: "id", Desc: true}
+
+	var attempts []models.LoginAttempt
+	if result := order.apply(query, cursor, nil).Limit(limit + 1).Find(&attempts); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching login attempts"})
+		return
+	}
+
+	attempts, hasNext, hasPrev := trimPage(attempts, limit, cursor)
+
+	response := gin.H{"attempts": attempts}
+	if len(attempts) > 0 {
+		setIDCursors(response, attempts[0].ID, attempts[len(attempts)-1].ID, hasNext, hasPrev)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -1,45 +1,150 @@
 package handlers
 
 import (
+	"io"
 	"net/http"
 	"strconv"
 	"time"
 	"uf-marketplace/database"
 	"uf-marketplace/models"
+	"uf-marketplace/notify"
+	"uf-marketplace/realtime"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	defaultNotificationLimit = 20
+	maxNotificationLimit     = 100
+
+	streamHeartbeatInterval = 25 * time.Second
+	streamReplayLimit       = 100
+)
+
 func GetNotifications(c *gin.Context) {
 	userID := c.GetUint("userID")
 	unreadOnly := c.DefaultQuery("unread", "false")
 
+	var errs queryErrors
+	limit := errs.queryInt(c, "limit", defaultNotificationLimit, 1, maxNotificationLimit)
+	cursor, _ := errs.queryCursor(c)
+	if errs.respond(c) {
+		return
+	}
+
 	query := database.DB.Where("user_id = ?", userID)
 
 	if unreadOnly == "true" {
 		query = query.Where("is_read = ?", false)
 	}
 
+	// Newest first; IDs follow creation order
+	order := keyset{IDColumn: "id", Desc: true}
+
 	var notifications []models.Notification
-	result := query.Order("created_at DESC").Find(&notifications)
+	result := order.apply(query, cursor, nil).Limit(limit + 1).Find(&notifications)
 
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching notifications"})
 		return
 	}
 
-	c.JSON(http.StatusOK, notifications)
+	notifications, hasNext, hasPrev := trimPage(notifications, limit, cursor)
+
+	response := gin.H{"notifications": notifications}
+	if len(notifications) > 0 {
+		setIDCursors(response, notifications[0].ID, notifications[len(notifications)-1].ID, hasNext, hasPrev)
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 func GetUnreadCount(c *gin.Context) {
 	userID := c.GetUint("userID")
 
-	var count int64
-	database.DB.Model(&models.Notification{}).
-		Where("user_id = ? AND is_read = ?", userID, false).
-		Count(&count)
+	c.JSON(http.StatusOK, gin.H{"count": notify.UnreadCount(userID)})
+}
+
+// StreamNotifications pushes each new notification to the owner as a
+// Server-Sent Event. Clients that reconnect with Last-Event-ID are first
+// sent whatever they missed.
+func StreamNotifications(c *gin.Context) {
+	userID := c.GetUint("userID")
 
-	c.JSON(http.StatusOK, gin.H{"count": count})
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	lastSentID, _ := strconv.ParseUint(lastEventID, 10, 32)
+
+	// Subscribe before replaying so nothing created in between is lost
+	events := realtime.NotificationBroker.Subscribe(userID, c.GetUint("sessionID"))
+	defer realtime.NotificationBroker.Unsubscribe(userID, events)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// Replay in batches until caught up, since live events skip anything
+	// at or below lastSentID
+	for lastSentID > 0 {
+		var missed []models.Notification
+		database.DB.
+			Where("user_id = ? AND id > ?", userID, lastSentID).
+			Order("id ASC").
+			Limit(streamReplayLimit).
+			Find(&missed)
+
+		for _, notification := range missed {
+			c.Render(-1, sse.Event{
+				Id:    strconv.Itoa(int(notification.ID)),
+				Event: "notification",
+				Data:  gin.H{"notification": notification},
+			})
+			lastSentID = uint64(notification.ID)
+		}
+		if len(missed) < streamReplayLimit {
+			break
+		}
+	}
+
+	c.Render(-1, sse.Event{
+		Event: "unread_count",
+		Data:  gin.H{"unread_count": notify.UnreadCount(userID)},
+	})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				// Fell behind or the session was signed out
+				return false
+			}
+			// Already delivered during the replay above
+			if event.ID > 0 && uint64(event.ID) <= lastSentID {
+				return true
+			}
+			sseEvent := sse.Event{Event: event.Name, Data: event.Data}
+			if event.ID > 0 {
+				sseEvent.Id = strconv.Itoa(int(event.ID))
+				lastSentID = uint64(event.ID)
+			}
+			c.Render(-1, sseEvent)
+			return true
+		case <-heartbeat.C:
+			// SSE comment line keeps proxies from closing an idle stream
+			io.WriteString(w, ": heartbeat\n\n")
+			return true
+		}
+	})
 }
 
 func MarkNotificationRead(c *gin.Context) {
@@ -68,6 +173,8 @@ func MarkNotificationRead(c *gin.Context) {
 		"read_at": now,
 	})
 
+	notify.PublishUnreadCount(userID)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Marked as read"})
 }
 
@@ -82,6 +189,8 @@ func MarkAllNotificationsRead(c *gin.Context) {
 			"read_at": now,
 		})
 
+	notify.PublishUnreadCount(userID)
+
 	c.JSON(http.StatusOK, gin.H{"message": "All notifications marked as read"})
 }
 
@@ -106,6 +215,7 @@ func DeleteNotification(c *gin.Context) {
 	}
 
 	database.DB.Delete(&notification)
+	notify.PublishUnreadCount(userID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Notification deleted"})
 }
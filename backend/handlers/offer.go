@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CreateOfferInput struct {
+	Amount  float64 `json:"amount" binding:"required,gt=0"`
+	Message string  `json:"message"`
+}
+
+type CounterOfferInput struct {
+	Amount  float64 `json:"amount" binding:"required,gt=0"`
+	Message string  `json:"message"`
+}
+
+func GetListingOffers(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	listing, ok := loadListingParam(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Preload("Buyer").Where("listing_id = ?", listing.ID)
+
+	// Sellers see every offer on their listing, buyers only see their own
+	if listing.SellerID != userID {
+		query = query.Where("buyer_id = ?", userID)
+	}
+
+	var offers []models.Offer
+	if result := query.Order("created_at DESC").Find(&offers); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching offers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, offers)
+}
+
+func CreateOffer(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	listing, ok := loadListingParam(c)
+	if !ok {
+		return
+	}
+
+	var input CreateOfferInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Offer amount must be greater than 0"})
+		return
+	}
+
+	if listing.SellerID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot make an offer on your own listing"})
+		return
+	}
+
+	if isBlockedEither(userID, listing.SellerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can't contact this seller"})
+		return
+	}
+
+	if listing.Status != models.StatusActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This listing is no longer accepting offers"})
+		return
+	}
+
+	// Only one open offer per buyer per listing
+	var openCount int64
+	database.DB.Model(&models.Offer{}).
+		Where("listing_id = ? AND buyer_id = ? AND status IN ?", listing.ID, userID,
+			[]models.OfferStatus{models.OfferPending, models.OfferCountered}).
+		Count(&openCount)
+	if openCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have an open offer on this listing"})
+		return
+	}
+
+	offer := models.Offer{
+		ListingID: listing.ID,
+		BuyerID:   userID,
+		Amount:    input.Amount,
+		Message:   input.Message,
+		Status:    models.OfferPending,
+	}
+
+	if result := database.DB.Create(&offer); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating offer"})
+		return
+	}
+
+	notify.Send(listing.SellerID, models.NotificationNewOffer, "New Offer",
+		fmt.Sprintf("You received an offer of $%.2f for: %s", offer.Amount, listing.Title),
+		notify.ListingLink(listing.ID))
+
+	database.DB.Preload("Buyer").First(&offer, offer.ID)
+
+	c.JSON(http.StatusCreated, offer)
+}
+
+func CounterOffer(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	listing, offer, ok := loadOfferParams(c)
+	if !ok {
+		return
+	}
+
+	var input CounterOfferInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Counter amount must be greater than 0"})
+		return
+	}
+
+	if listing.SellerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the seller can counter an offer"})
+		return
+	}
+
+	if offer.Status != models.OfferPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only pending offers can be countered"})
+		return
+	}
+
+	now := time.Now()
+	offer.Status = models.OfferCountered
+	offer.CounterAmount = &input.Amount
+	offer.CounterNote = input.Message
+	offer.RespondedAt = &now
+
+	if result := database.DB.Save(&offer); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating offer"})
+		return
+	}
+
+	notify.Send(offer.BuyerID, models.NotificationOfferCountered, "Offer Countered",
+		fmt.Sprintf("The seller countered your offer on %s with $%.2f", listing.Title, input.Amount),
+		notify.ListingLink(listing.ID))
+
+	database.DB.Preload("Buyer").First(&offer, offer.ID)
+
+	c.JSON(http.StatusOK, offer)
+}
+
+// errOfferChanged means someone responded to the offer after it was loaded.
+var errOfferChanged = errors.New("offer changed")
+
+func AcceptOffer(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	listing, offer, ok := loadOfferParams(c)
+	if !ok {
+		return
+	}
+
+	// The seller accepts a pending offer; the buyer accepts a counter
+	var recipientID uint
+	var actor models.StatusActor
+	switch {
+	case offer.Status == models.OfferPending && listing.SellerID == userID:
+		recipientID = offer.BuyerID
+		actor = models.ActorSeller
+	case offer.Status == models.OfferCountered && offer.BuyerID == userID:
+		recipientID = listing.SellerID
+		actor = models.ActorBuyer
+	case offer.BuyerID != userID && listing.SellerID != userID:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to respond to this offer"})
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This offer is not waiting on your response"})
+		return
+	}
+
+	if !models.CanTransition(listing.Status, models.StatusSold, actor) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This listing is no longer available"})
+		return
+	}
+
+	// The buyer's chat about the listing, if they had one, links the sale to it
+	var chatID *uint
+	var chat models.Chat
+	if result := database.DB.Where("listing_id = ? AND buyer_id = ?", listing.ID, offer.BuyerID).First(&chat); result.Error == nil {
+		chatID = &chat.ID
+	}
+
+	var sale models.Transaction
+	var declined []models.Offer
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Only accept it if nobody responded to it since we loaded it
+		now := time.Now()
+		result := tx.Model(&models.Offer{}).
+			Where("id = ? AND status = ?", offer.ID, offer.Status).
+			Updates(map[string]interface{}{"status": models.OfferAccepted, "responded_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errOfferChanged
+		}
+		offer.Status = models.OfferAccepted
+		offer.RespondedAt = &now
+
+		var err error
+		sale, declined, err = recordSale(tx, &listing, actor, userID, offer.BuyerID, offer.FinalAmount(), chatID, &offer.ID)
+		return err
+	})
+	if errors.Is(err, errOfferChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This offer is no longer waiting on your response"})
+		return
+	}
+	if errors.Is(err, errListingStatusChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This listing is no longer available"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error accepting offer"})
+		return
+	}
+
+	notify.Send(recipientID, models.NotificationOfferAccepted, "Offer Accepted",
+		fmt.Sprintf("An offer of $%.2f was accepted for: %s", offer.FinalAmount(), listing.Title),
+		notify.ListingLink(listing.ID))
+	notifySale(listing, sale, declined)
+
+	database.DB.Preload("Buyer").First(&offer, offer.ID)
+
+	c.JSON(http.StatusOK, offer)
+}
+
+func DeclineOffer(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	listing, offer, ok := loadOfferParams(c)
+	if !ok {
+		return
+	}
+
+	// The seller declines a pending offer; the buyer declines a counter
+	var recipientID uint
+	var message string
+	switch {
+	case offer.Status == models.OfferPending && listing.SellerID == userID:
+		recipientID = offer.BuyerID
+		message = "The seller declined your offer for: " + listing.Title
+	case offer.Status == models.OfferCountered && offer.BuyerID == userID:
+		recipientID = listing.SellerID
+		message = "The buyer declined your counter offer for: " + listing.Title
+	case offer.BuyerID != userID && listing.SellerID != userID:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to respond to this offer"})
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This offer is not waiting on your response"})
+		return
+	}
+
+	now := time.Now()
+	offer.Status = models.OfferDeclined
+	offer.RespondedAt = &now
+
+	if result := database.DB.Save(&offer); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating offer"})
+		return
+	}
+
+	notify.Send(recipientID, models.NotificationOfferDeclined, "Offer Declined", message,
+		notify.ListingLink(listing.ID))
+
+	database.DB.Preload("Buyer").First(&offer, offer.ID)
+
+	c.JSON(http.StatusOK, offer)
+}
+
+// loadListingParam resolves the :id route param to a listing, writing the
+// error response itself when the listing can't be found.
+func loadListingParam(c *gin.Context) (models.Listing, bool) {
+	var listing models.Listing
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return listing, false
+	}
+
+	if result := database.DB.First(&listing, id); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return listing, false
+	}
+
+	return listing, true
+}
+
+// loadOfferParams resolves the :id and :offer_id route params, making sure the
+// offer actually belongs to the listing in the URL.
+func loadOfferParams(c *gin.Context) (models.Listing, models.Offer, bool) {
+	var offer models.Offer
+
+	listing, ok := loadListingParam(c)
+	if !ok {
+		return listing, offer, false
+	}
+
+	offerID, err := strconv.ParseUint(c.Param("offer_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offer ID"})
+		return listing, offer, false
+	}
+
+	if result := database.DB.Where("listing_id = ?", listing.ID).First(&offer, offerID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Offer not found"})
+		return listing, offer, false
+	}
+
+	return listing, offer, true
+}
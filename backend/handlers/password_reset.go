@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/mailer"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+	"uf-marketplace/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	passwordResetTTL      = time.Hour
+	passwordResetWindow   = time.Hour
+	passwordResetPerEmail = 3
+	passwordResetPerIP    = 10
+)
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordInput struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// ForgotPassword emails a one-time reset link. It answers the same way
+// whether or not the account exists so it can't be used to probe emails.
+func ForgotPassword(c *gin.Context) {
+	var input ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please enter a valid email address"})
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	ip := c.ClientIP()
+	since := time.Now().Add(-passwordResetWindow)
+
+	var emailCount, ipCount int64
+	database.DB.Model(&models.PasswordReset{}).Where("email = ? AND created_at > ?", email, since).Count(&emailCount)
+	database.DB.Model(&models.PasswordReset{}).Where("ip_address = ? AND created_at > ?", ip, since).Count(&ipCount)
+	if emailCount >= passwordResetPerEmail || ipCount >= passwordResetPerIP {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many reset requests. Please try again later."})
+		return
+	}
+
+	reset := models.PasswordReset{
+		Email:     email,
+		IPAddress: ip,
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+
+	var user models.User
+	var token string
+	if result := database.DB.Where("email = ?", email).First(&user); result.Error == nil {
+		var err error
+		token, err = utils.RandomString(32)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating reset token"})
+			return
+		}
+		reset.UserID = user.ID
+		reset.TokenHash = utils.HashToken(token)
+	}
+
+	if result := database.DB.Create(&reset); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing request"})
+		return
+	}
+
+	if token != "" {
+		sendMail(mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your UF Market password",
+			Body: "Hi " + user.FirstName + ",\n\n" +
+				"Someone asked to reset the password for your UF Market account. Choose a new password here:\n\n" +
+				appURL("/reset-password?token="+url.QueryEscape(token)) + "\n\n" +
+				"The link expires in 1 hour and can only be used once. If this wasn't you, you can ignore this email.\n",
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If an account exists for that email, a reset link is on its way"})
+}
+
+// ResetPassword sets a new password using an emailed reset token. Every
+// existing session is signed out since the old password may have leaked.
+func ResetPassword(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		if strings.Contains(err.Error(), "NewPassword") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 6 characters"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reset token is required"})
+		return
+	}
+
+	var reset models.PasswordReset
+	if result := database.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?",
+		utils.HashToken(input.Token), time.Now()).First(&reset); result.Error != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This reset link is invalid or has expired"})
+		return
+	}
+
+	var user models.User
+	if result := database.DB.First(&user, reset.UserID); result.Error != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This reset link is invalid or has expired"})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(input.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating password"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// Claim the token first so two concurrent resets can't both win
+		result := tx.Model(&models.PasswordReset{}).
+			Where("id = ? AND used_at IS NULL", reset.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// Any other outstanding links for the account are now stale
+		if err := tx.Model(&models.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"password": hashedPassword}
+		// Following the emailed link proves the address is theirs
+		if !user.IsVerified() {
+			updates["email_verified_at"] = now
+			updates["verification_nonce"] = ""
+		}
+		return tx.Model(&user).Updates(updates).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This reset link is invalid or has expired"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating password"})
+		return
+	}
+
+	revoked := revokeUserSessions(user.ID, 0)
+	// Following the link proves it's the owner, so lift any sign-in lockout
+	clearLoginFailures(user.Email)
+	log.Printf("Password reset for user %d from %s, %d sessions revoked", user.ID, c.ClientIP(), revoked)
+
+	notify.Send(user.ID, models.NotificationSecurity, "Password Changed",
+		"Your password was reset and all devices were signed out. If this wasn't you, reset it again right away.",
+		"/settings")
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Your UF Market password was changed",
+		Body: "Hi " + user.FirstName + ",\n\n" +
+			"The password for your UF Market account was just reset and every device was signed out.\n\n" +
+			"If you didn't do this, request a new reset link right away:\n\n" +
+			appURL("/forgot-password") + "\n",
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset. Please sign in with your new password."})
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"uf-marketplace/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FieldError describes a single rejected query or body parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// queryErrors collects parameter problems so a request can report all of
+// them in one 400 response instead of failing on the first.
+type queryErrors []FieldError
+
+func (e *queryErrors) add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
+// respond writes a structured 400 and reports whether there was anything to
+// report.
+func (e queryErrors) respond(c *gin.Context) bool {
+	if len(e) == 0 {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "Invalid query parameters",
+		"details": e,
+	})
+	return true
+}
+
+// queryInt parses an optional integer param within [min, max].
+func (e *queryErrors) queryInt(c *gin.Context, field string, fallback, min, max int) int {
+	raw := c.Query(field)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		e.add(field, "must be a whole number")
+		return fallback
+	}
+	if value < min || value > max {
+		e.add(field, "must be between "+strconv.Itoa(min)+" and "+strconv.Itoa(max))
+		return fallback
+	}
+	return value
+}
+
+// queryPrice parses an optional non-negative price param. ok is false when
+// the param was absent or invalid.
+func (e *queryErrors) queryPrice(c *gin.Context, field string) (value float64, ok bool) {
+	raw := c.Query(field)
+	if raw == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		e.add(field, "must be a number")
+		return 0, false
+	}
+	if value < 0 {
+		e.add(field, "must not be negative")
+		return 0, false
+	}
+	return value, true
+}
+
+// queryID parses an optional positive ID param.
+func (e *queryErrors) queryID(c *gin.Context, field string) (uint, bool) {
+	raw := c.Query(field)
+	if raw == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || value == 0 {
+		e.add(field, "must be a positive ID")
+		return 0, false
+	}
+	return uint(value), true
+}
+
+// queryCursor decodes the optional "cursor" param.
+func (e *queryErrors) queryCursor(c *gin.Context) (utils.Cursor, bool) {
+	raw := c.Query("cursor")
+	if raw == "" {
+		return utils.Cursor{}, false
+	}
+	cursor, err := utils.DecodeCursor(raw)
+	if err != nil {
+		e.add("cursor", "is invalid or has been tampered with")
+		return utils.Cursor{}, false
+	}
+	return cursor, true
+}
+
+// keyset orders a query by an optional sort column plus the ID as a tie
+// breaker, and narrows it to the rows on one side of a cursor.
+type keyset struct {
+	Column   string // sort column, empty when ordering by ID alone
+	IDColumn string
+	Desc     bool
+}
+
+// apply adds the cursor condition and ordering. Paging backwards flips the
+// ordering, so the caller must reverse the rows it gets back (see trimPage).
+// A zero cursor ID means "no bound", e.g. the newest page of a chat.
+func (k keyset) apply(query *gorm.DB, cursor utils.Cursor, value interface{}) *gorm.DB {
+	desc := k.Desc != cursor.Before
+
+	direction, op := "ASC", ">"
+	if desc {
+		direction, op = "DESC", "<"
+	}
+
+	if cursor.ID > 0 {
+		if k.Column == "" {
+			query = query.Where(k.IDColumn+" "+op+" ?", cursor.ID)
+		} else {
+			query = query.Where("("+k.Column+" "+op+" ? OR ("+k.Column+" = ? AND "+k.IDColumn+" "+op+" ?))",
+				value, value, cursor.ID)
+		}
+	}
+
+	if k.Column != "" {
+		query = query.Order(k.Column + " " + direction)
+	}
+	return query.Order(k.IDColumn + " " + direction)
+}
+
+// trimPage takes the limit+1 rows fetched by a keyset query and returns the
+// page in display order, plus whether more rows exist past either end.
+func trimPage[T any](rows []T, limit int, cursor utils.Cursor) (page []T, hasNext, hasPrev bool) {
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+
+	bounded := cursor.ID > 0
+	if cursor.Before {
+		slices.Reverse(rows)
+		return rows, bounded, more
+	}
+	return rows, more, bounded
+}
+
+// setIDCursors adds next_cursor and prev_cursor to a response for a page
+// that is ordered by ID alone.
+func setIDCursors(response gin.H, firstID, lastID uint, hasNext, hasPrev bool) {
+	if hasNext && lastID > 0 {
+		response["next_cursor"] = utils.EncodeCursor(utils.Cursor{ID: lastID})
+	}
+	if hasPrev && firstID > 0 {
+		response["prev_cursor"] = utils.EncodeCursor(utils.Cursor{ID: firstID, Before: true})
+	}
+}
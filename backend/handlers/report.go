@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// reportHideThreshold is how many different verified users have to report
+// a listing before it is hidden while it waits for review.
+const reportHideThreshold = 3
+
+type CreateReportInput struct {
+	TargetType models.ReportTarget `json:"target_type" binding:"required"`
+	TargetID   uint                `json:"target_id" binding:"required"`
+	Reason     models.ReportReason `json:"reason" binding:"required"`
+	Details    string              `json:"details" binding:"max=2000"`
+}
+
+type UpdateReportInput struct {
+	Status models.ReportStatus `json:"status" binding:"required"`
+	Note   string              `json:"note"`
+}
+
+func CreateReport(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var input CreateReportInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_type, target_id and reason are required"})
+		return
+	}
+
+	if !slices.Contains(models.ReportReasons, input.Reason) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report reason"})
+		return
+	}
+
+	ownerID, ok := reportTargetOwner(c, input.TargetType, input.TargetID)
+	if !ok {
+		return
+	}
+	if ownerID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot report yourself"})
+		return
+	}
+
+	// One open report per user per target, so nobody can hide a listing
+	// on their own
+	var existing int64
+	database.DB.Model(&models.Report{}).
+		Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status IN ?", userID,
+			input.TargetType, input.TargetID, []models.ReportStatus{models.ReportOpen, models.ReportReviewing}).
+		Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already reported this"})
+		return
+	}
+
+	report := models.Report{
+		ReporterID: userID,
+		TargetType: input.TargetType,
+		TargetID:   input.TargetID,
+		Reason:     input.Reason,
+		Details:    strings.TrimSpace(input.Details),
+		Status:     models.ReportOpen,
+	}
+
+	if result := database.DB.Create(&report); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating report"})
+		return
+	}
+
+	if report.TargetType == models.ReportTargetListing {
+		hideReportedListing(report.TargetID)
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+// GetMyReports lists the reports the current user has filed.
+func GetMyReports(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var reports []models.Report
+	if result := database.DB.Where("reporter_id = ?", userID).Order("created_at DESC").Find(&reports); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// GetReportQueue pages through reports oldest first so moderators work the
+// queue in order. Defaults to reports that still need attention.
+func GetReportQueue(c *gin.Context) {
+	var errs queryErrors
+	limit := errs.queryInt(c, "limit", defaultAdminPageLimit, 1, maxAdminPageLimit)
+	cursor, _ := errs.queryCursor(c)
+	status := c.Query("status")
+	if status != "" && status != "all" && !slices.Contains([]models.ReportStatus{models.ReportOpen,
+		models.ReportReviewing, models.ReportActioned, models.ReportDismissed}, models.ReportStatus(status)) {
+		errs.add("status", "must be open, reviewing, actioned, dismissed or all")
+	}
+	targetType := c.Query("target_type")
+	if targetType != "" && !isReportTarget(models.ReportTarget(targetType)) {
+		errs.add("target_type", "must be listing, user or message")
+	}
+	if errs.respond(c) {
+		return
+	}
+
+	query := database.DB.Preload("Reporter")
+	switch status {
+	case "":
+		query = query.Where("status IN ?", []models.ReportStatus{models.ReportOpen, models.ReportReviewing})
+	case "all":
+	default:
+		query = query.Where("status = ?", status)
+	}
+	if targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+
+	order := keyset{IDColumn: "id"}
+
+	var reports []models.Report
+	if result := order.apply(query, cursor, nil).Limit(limit + 1).Find(&reports); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching reports"})
+		return
+	}
+
+	reports, hasNext, hasPrev := trimPage(reports, limit, cursor)
+
+	response := gin.H{"reports": reports}
+	if len(reports) > 0 {
+		setIDCursors(response, reports[0].ID, reports[len(reports)-1].ID, hasNext, hasPrev)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetReport returns a report together with what was reported and every
+// other report against the same target.
+func GetReport(c *gin.Context) {
+	report, ok := loadReportParam(c)
+	if !ok {
+		return
+	}
+
+	var related []models.Report
+	database.DB.Preload("Reporter").
+		Where("target_type = ? AND target_id = ? AND id != ?", report.TargetType, report.TargetID, report.ID).
+		Order("created_at DESC").Find(&related)
+
+	c.JSON(http.StatusOK, gin.H{
+		"report":  report,
+		"target":  reportTarget(report.TargetType, report.TargetID),
+		"related": related,
+	})
+}
+
+// UpdateReport moves a report through the review workflow. Closing the
+// last open report on a hidden listing puts the listing back.
+func UpdateReport(c *gin.Context) {
+	report, ok := loadReportParam(c)
+	if !ok {
+		return
+	}
+
+	var input UpdateReportInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status is required"})
+		return
+	}
+
+	if !report.CanMoveTo(input.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot move a " + string(report.Status) + " report to " + string(input.Status)})
+		return
+	}
+
+	previous := report.Status
+	reviewerID := c.GetUint("userID")
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		report.Status = input.Status
+		report.ReviewerID = &reviewerID
+		if input.Note != "" {
+			report.ResolutionNote = input.Note
+		}
+		if !report.IsOpen() {
+			now := time.Now()
+			report.ResolvedAt = &now
+		}
+		if err := tx.Save(&report).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditReportUpdate, "report", report.ID, input.Note,
+			gin.H{"from": previous, "to": report.Status, "target_type": report.TargetType, "target_id": report.TargetID})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating report"})
+		return
+	}
+
+	// A dismissed report shouldn't keep the listing hidden. An actioned one
+	// leaves it hidden until a moderator removes or restores it
+	if report.TargetType == models.ReportTargetListing && report.Status == models.ReportDismissed {
+		unhideReviewedListing(report.TargetID)
+	}
+
+	if !report.IsOpen() {
+		message := "Thanks for your report. A moderator reviewed it and took action."
+		if report.Status == models.ReportDismissed {
+			message = "Thanks for your report. A moderator reviewed it and found no rule was broken."
+		}
+		notify.Send(report.ReporterID, models.NotificationReportUpdate, "Report Reviewed", message, "")
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// hideReportedListing hides a listing once enough different verified users
+// have open reports against it, so throwaway accounts can't take it down.
+func hideReportedListing(listingID uint) {
+	var reporters int64
+	database.DB.Model(&models.Report{}).
+		Joins("JOIN users ON users.id = reports.reporter_id AND users.email_verified_at IS NOT NULL").
+		Where("reports.target_type = ? AND reports.target_id = ? AND reports.status IN ?", models.ReportTargetListing, listingID,
+			[]models.ReportStatus{models.ReportOpen, models.ReportReviewing}).
+		Distinct("reports.reporter_id").Count(&reporters)
+	if reporters < reportHideThreshold {
+		return
+	}
+
+	var listing models.Listing
+	if result := database.DB.First(&listing, listingID); result.Error != nil || listing.HiddenAt != nil {
+		return
+	}
+
+	result := database.DB.Model(&models.Listing{}).
+		Where("id = ? AND hidden_at IS NULL", listingID).
+		Update("hidden_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	notify.Send(listing.SellerID, models.NotificationListingHidden, "Listing Under Review",
+		"Your listing \""+listing.Title+"\" has been hidden while moderators review reports about it",
+		notify.ListingLink(listing.ID))
+}
+
+// unhideReviewedListing shows a hidden listing again once the reports that
+// hid it have all been dismissed. Open reports keep it hidden for review,
+// and one actioned since it was hidden keeps it hidden until a moderator
+// restores it.
+func unhideReviewedListing(listingID uint) {
+	var listing models.Listing
+	if result := database.DB.First(&listing, listingID); result.Error != nil || listing.HiddenAt == nil {
+		return
+	}
+
+	var pending int64
+	database.DB.Model(&models.Report{}).
+		Where("target_type = ? AND target_id = ?", models.ReportTargetListing, listingID).
+		Where("status IN ? OR (status = ? AND resolved_at >= ?)",
+			[]models.ReportStatus{models.ReportOpen, models.ReportReviewing}, models.ReportActioned, *listing.HiddenAt).
+		Count(&pending)
+	if pending > 0 {
+		return
+	}
+
+	database.DB.Model(&models.Listing{}).Where("id = ?", listingID).Update("hidden_at", nil)
+}
+
+// reportTargetOwner checks that the reported item exists and that the
+// current user can see it, returning the ID of the user it belongs to.
+func reportTargetOwner(c *gin.Context, targetType models.ReportTarget, targetID uint) (uint, bool) {
+	switch targetType {
+	case models.ReportTargetListing:
+		var listing models.Listing
+		if result := database.DB.First(&listing, targetID); result.Error != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+			return 0, false
+		}
+		return listing.SellerID, true
+
+	case models.ReportTargetUser:
+		var user models.User
+		if result := database.DB.First(&user, targetID); result.Error != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return 0, false
+		}
+		return user.ID, true
+
+	case models.ReportTargetMessage:
+		// Only someone in the conversation can report a message from it
+		var message models.Message
+		if result := database.DB.Preload("Chat").First(&message, targetID); result.Error != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return 0, false
+		}
+		userID := c.GetUint("userID")
+		if message.Chat.BuyerID != userID && message.Chat.SellerID != userID {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return 0, false
+		}
+		return message.SenderID, true
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": "target_type must be listing, user or message"})
+	return 0, false
+}
+
+// reportTarget loads the reported item for moderators, including soft
+// deleted ones.
+func reportTarget(targetType models.ReportTarget, targetID uint) interface{} {
+	switch targetType {
+	case models.ReportTargetListing:
+		var listing models.Listing
+		if database.DB.Unscoped().Preload("Images").Preload("Seller").First(&listing, targetID).Error == nil {
+			return listing
+		}
+	case models.ReportTargetUser:
+		var user models.User
+		if database.DB.Unscoped().First(&user, targetID).Error == nil {
+			return toAdminUser(user)
+		}
+	case models.ReportTargetMessage:
+		var message models.Message
+		if database.DB.Unscoped().Preload("Sender").First(&message, targetID).Error == nil {
+			return message
+		}
+	}
+	return nil
+}
+
+func isReportTarget(targetType models.ReportTarget) bool {
+	return targetType == models.ReportTargetListing || targetType == models.ReportTargetUser ||
+		targetType == models.ReportTargetMessage
+}
+
+// loadReportParam resolves the :id route param to a report.
+func loadReportParam(c *gin.Context) (models.Report, bool) {
+	var report models.Report
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return report, false
+	}
+
+	if result := database.DB.Preload("Reporter").First(&report, id); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return report, false
+	}
+
+	return report, true
+}
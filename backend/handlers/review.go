@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultReviewLimit = 20
+	maxReviewLimit     = 100
+)
+
+type CreateReviewInput struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=2000"`
+}
+
+type ReviewReplyInput struct {
+	Reply string `json:"reply" binding:"required,max=2000"`
+}
+
+// UserProfileResponse is a public profile with the user's reputation.
+type UserProfileResponse struct {
+	models.UserResponse
+	models.RatingSummary
+}
+
+// CreateReview lets the buyer or seller of a sold listing rate the other.
+// The sale has to have gone through a chat between the two.
+func CreateReview(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	listing, ok := loadListingParam(c)
+	if !ok {
+		return
+	}
+
+	var input CreateReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rating must be between 1 and 5"})
+		return
+	}
+
+	var sale models.Transaction
+	if result := database.DB.Where("listing_id = ?", listing.ID).First(&sale); result.Error != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reviews can only be left once a listing is sold"})
+		return
+	}
+
+	var revieweeID uint
+	var role string
+	switch userID {
+	case sale.SellerID:
+		revieweeID, role = sale.BuyerID, "buyer"
+	case sale.BuyerID:
+		revieweeID, role = sale.SellerID, "seller"
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the buyer and seller can review this sale"})
+		return
+	}
+
+	if sale.ChatID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reviews need a chat between the buyer and seller"})
+		return
+	}
+
+	var existing int64
+	database.DB.Model(&models.Review{}).Where("listing_id = ? AND reviewer_id = ?", listing.ID, userID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already reviewed this sale"})
+		return
+	}
+
+	review := models.Review{
+		ListingID:  listing.ID,
+		ChatID:     *sale.ChatID,
+		ReviewerID: userID,
+		RevieweeID: revieweeID,
+		Role:       role,
+		Rating:     input.Rating,
+		Comment:    strings.TrimSpace(input.Comment),
+	}
+
+	if result := database.DB.Create(&review); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating review"})
+		return
+	}
+
+	notify.Send(revieweeID, models.NotificationNewReview, "New Review",
+		fmt.Sprintf("You received a %d-star review for: %s", review.Rating, listing.Title),
+		"/profile")
+
+	database.DB.Preload("Reviewer").Preload("Listing").First(&review, review.ID)
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// GetUserReviews lists the reviews a user has received, newest first.
+func GetUserReviews(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var errs queryErrors
+	limit := errs.queryInt(c, "limit", defaultReviewLimit, 1, maxReviewLimit)
+	cursor, _ := errs.queryCursor(c)
+	role := c.Query("role")
+	if role != "" && role != "buyer" && role != "seller" {
+		errs.add("role", "must be buyer or seller")
+	}
+	if errs.respond(c) {
+		return
+	}
+
+	query := database.DB.Preload("Reviewer").Preload("Listing").Where("reviewee_id = ?", id)
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	order := keyset{IDColumn: "id", Desc: true}
+
+	var reviews []models.Review
+	if result := order.apply(query, cursor, nil).Limit(limit + 1).Find(&reviews); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching reviews"})
+		return
+	}
+
+	reviews, hasNext, hasPrev := trimPage(reviews, limit, cursor)
+
+	response := gin.H{"reviews": reviews, "summary": ratingSummary(uint(id))}
+	if len(reviews) > 0 {
+		setIDCursors(response, reviews[0].ID, reviews[len(reviews)-1].ID, hasNext, hasPrev)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ReplyToReview posts or edits the reviewed user's public answer.
+func ReplyToReview(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	var review models.Review
+	if result := database.DB.First(&review, id); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+
+	if review.RevieweeID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the reviewed user can reply"})
+		return
+	}
+
+	var input ReviewReplyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reply is required"})
+		return
+	}
+
+	now := time.Now()
+	firstReply := review.RepliedAt == nil
+	review.Reply = strings.TrimSpace(input.Reply)
+	review.RepliedAt = &now
+
+	if result := database.DB.Save(&review); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving reply"})
+		return
+	}
+
+	if firstReply {
+		notify.Send(review.ReviewerID, models.NotificationReviewReply, "Review Reply",
+			"Someone replied to your review", "/profile")
+	}
+
+	database.DB.Preload("Reviewer").Preload("Listing").First(&review, review.ID)
+
+	c.JSON(http.StatusOK, review)
+}
+
+// ratingSummary averages the ratings a user has received, rounded to one
+// decimal place.
+func ratingSummary(userID uint) models.RatingSummary {
+	var summary models.RatingSummary
+	database.DB.Model(&models.Review{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("reviewee_id = ?", userID).
+		Scan(&summary)
+	summary.Average = math.Round(summary.Average*10) / 10
+	return summary
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTransactionLimit = 20
+	maxTransactionLimit     = 100
+)
+
+type MarkSoldInput struct {
+	BuyerID    uint     `json:"buyer_id" binding:"required"`
+	FinalPrice *float64 `json:"final_price" binding:"omitempty,gt=0"`
+}
+
+// MarkListingSold records the sale of a listing to one of the users who
+// chatted with the seller about it. The listing can't be edited afterwards.
+func MarkListingSold(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	listing, ok := loadListingParam(c)
+	if !ok {
+		return
+	}
+
+	var input MarkSoldInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "buyer_id is required and final_price must be greater than 0"})
+		return
+	}
+
+	if listing.SellerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to update this listing"})
+		return
+	}
+
+	if !models.CanTransition(listing.Status, models.StatusSold, models.ActorSeller) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This listing is " + string(listing.Status) + " and can't be marked as sold"})
+		return
+	}
+
+	var chat models.Chat
+	if result := database.DB.Where("listing_id = ? AND buyer_id = ?", listing.ID, input.BuyerID).First(&chat); result.Error != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The buyer must be someone who has chatted with you about this listing"})
+		return
+	}
+
+	price := listing.Price
+	if input.FinalPrice != nil {
+		price = *input.FinalPrice
+	}
+
+	var sale models.Transaction
+	var declined []models.Offer
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		sale, declined, err = recordSale(tx, &listing, models.ActorSeller, userID, input.BuyerID, price, &chat.ID, nil)
+		return err
+	})
+	if errors.Is(err, errListingStatusChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This listing is no longer available"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error marking listing as sold"})
+		return
+	}
+
+	notify.Send(sale.BuyerID, models.NotificationListingSold, "Purchase Confirmed",
+		fmt.Sprintf("%s was marked as sold to you for $%.2f", listing.Title, sale.FinalPrice),
+		notify.ListingLink(listing.ID))
+	notifySale(listing, sale, declined)
+
+	database.DB.Preload("Listing").Preload("Seller").Preload("Buyer").First(&sale, sale.ID)
+
+	c.JSON(http.StatusCreated, sale)
+}
+
+// GetMyTransactions lists the current user's purchases and sales, newest
+// first.
+func GetMyTransactions(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var errs queryErrors
+	limit := errs.queryInt(c, "limit", defaultTransactionLimit, 1, maxTransactionLimit)
+	cursor, _ := errs.queryCursor(c)
+	role := c.Query("role")
+	if role != "" && role != "buyer" && role != "seller" {
+		errs.add("role", "must be buyer or seller")
+	}
+	if errs.respond(c) {
+		return
+	}
+
+	query := database.DB.Preload("Listing.Images").Preload("Seller").Preload("Buyer")
+	switch role {
+	case "buyer":
+		query = query.Where("buyer_id = ?", userID)
+	case "seller":
+		query = query.Where("seller_id = ?", userID)
+	default:
+		query = query.Where("buyer_id = ? OR seller_id = ?", userID, userID)
+	}
+
+	order := keyset{IDColumn: "id", Desc: true}
+
+	var sales []models.Transaction
+	if result := order.apply(query, cursor, nil).Limit(limit + 1).Find(&sales); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching transactions"})
+		return
+	}
+
+	sales, hasNext, hasPrev := trimPage(sales, limit, cursor)
+
+	response := gin.H{"transactions": sales}
+	if len(sales) > 0 {
+		setIDCursors(response, sales[0].ID, sales[len(sales)-1].ID, hasNext, hasPrev)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// recordSale marks listing sold to buyerID inside tx on behalf of actor,
+// records the Transaction and declines every other open offer, which it
+// returns so the caller can notify their buyers once tx commits.
+func recordSale(tx *gorm.DB, listing *models.Listing, actor models.StatusActor, changedByID, buyerID uint, price float64, chatID, offerID *uint) (models.Transaction, []models.Offer, error) {
+	sale := models.Transaction{
+		ListingID:   listing.ID,
+		SellerID:    listing.SellerID,
+		BuyerID:     buyerID,
+		ChatID:      chatID,
+		OfferID:     offerID,
+		ListedPrice: listing.Price,
+		FinalPrice:  price,
+	}
+	if err := tx.Create(&sale).Error; err != nil {
+		return sale, nil, err
+	}
+
+	if err := tx.Model(listing).Update("buyer_id", buyerID).Error; err != nil {
+		return sale, nil, err
+	}
+	if err := setListingStatus(tx, listing, models.StatusSold, actor, changedByID, ""); err != nil {
+		return sale, nil, err
+	}
+
+	// Every other open offer on the listing is now moot
+	query := tx.Where("listing_id = ? AND status IN ?", listing.ID,
+		[]models.OfferStatus{models.OfferPending, models.OfferCountered})
+	if offerID != nil {
+		query = query.Where("id != ?", *offerID)
+	}
+	var declined []models.Offer
+	if err := query.Find(&declined).Error; err != nil {
+		return sale, nil, err
+	}
+	if len(declined) > 0 {
+		ids := make([]uint, len(declined))
+		for i, other := range declined {
+			ids[i] = other.ID
+		}
+		if err := tx.Model(&models.Offer{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":       models.OfferDeclined,
+			"responded_at": sale.CreatedAt,
+		}).Error; err != nil {
+			return sale, nil, err
+		}
+	}
+
+	return sale, declined, nil
+}
+
+// notifySale tells everyone else who was interested in a listing that it
+// has gone: every user chatting with the seller about it gets a sold
+// notice, and buyers whose offers were declined are told that as well.
+func notifySale(listing models.Listing, sale models.Transaction, declined []models.Offer) {
+	parties := map[uint]bool{sale.BuyerID: true, sale.SellerID: true}
+
+	declinedBuyers := map[uint]bool{}
+	for _, other := range declined {
+		if parties[other.BuyerID] || declinedBuyers[other.BuyerID] {
+			continue
+		}
+		declinedBuyers[other.BuyerID] = true
+		notify.Send(other.BuyerID, models.NotificationOfferDeclined, "Offer Declined",
+			"The seller sold this listing to another buyer: "+listing.Title,
+			notify.ListingLink(listing.ID))
+	}
+
+	var chats []models.Chat
+	database.DB.Where("listing_id = ?", listing.ID).Find(&chats)
+	for _, chat := range chats {
+		if parties[chat.BuyerID] {
+			continue
+		}
+		parties[chat.BuyerID] = true
+		notify.Send(chat.BuyerID, models.NotificationListingSold, "Listing Sold",
+			"A listing you asked about has been sold: "+listing.Title,
+			notify.ListingLink(listing.ID))
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+	"uf-marketplace/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSavedSearches caps how many searches one user can save.
+const maxSavedSearches = 20
+
+type SavedSearchInput struct {
+	Name       string                `json:"name"`
+	Search     string                `json:"search"`
+	CategoryID *uint                 `json:"category_id"`
+	MinPrice   *float64              `json:"min_price" binding:"omitempty,gte=0"`
+	MaxPrice   *float64              `json:"max_price" binding:"omitempty,gte=0"`
+	Condition  string                `json:"condition"`
+	Alerts     models.AlertFrequency `json:"alerts"`
+}
+
+// GetSavedSearches lists the current user's saved searches, newest first.
+func GetSavedSearches(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var searches []models.SavedSearch
+	if result := database.DB.Where("user_id = ?", userID).Order("id DESC").Find(&searches); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching saved searches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, searches)
+}
+
+// CreateSavedSearch saves a set of GetListings filters. New listings that
+// match are announced as they're published, or in a daily digest.
+func CreateSavedSearch(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var input SavedSearchInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prices must be 0 or more"})
+		return
+	}
+
+	var count int64
+	database.DB.Model(&models.SavedSearch{}).Where("user_id = ?", userID).Count(&count)
+	if count >= maxSavedSearches {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("You can save up to %d searches", maxSavedSearches)})
+		return
+	}
+
+	search := models.SavedSearch{UserID: userID}
+	if !applySavedSearchInput(c, &search, input) {
+		return
+	}
+
+	if result := database.DB.Create(&search); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving search"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, search)
+}
+
+// UpdateSavedSearch replaces a saved search's filters and alert setting.
+func UpdateSavedSearch(c *gin.Context) {
+	search, ok := loadSavedSearchParam(c)
+	if !ok {
+		return
+	}
+
+	var input SavedSearchInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prices must be 0 or more"})
+		return
+	}
+
+	if !applySavedSearchInput(c, &search, input) {
+		return
+	}
+
+	if result := database.DB.Save(&search); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating saved search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, search)
+}
+
+func DeleteSavedSearch(c *gin.Context) {
+	search, ok := loadSavedSearchParam(c)
+	if !ok {
+		return
+	}
+
+	database.DB.Where("saved_search_id = ?", search.ID).Delete(&models.SavedSearchMatch{})
+	if result := database.DB.Delete(&search); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting saved search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted"})
+}
+
+// applySavedSearchInput validates input the way GetListings validates its
+// filters and copies it onto search. It responds and returns false when
+// something is wrong.
+func applySavedSearchInput(c *gin.Context, search *models.SavedSearch, input SavedSearchInput) bool {
+	input.Search = strings.TrimSpace(input.Search)
+	input.Name = strings.TrimSpace(input.Name)
+
+	if input.Search == "" && input.CategoryID == nil && input.MinPrice == nil && input.MaxPrice == nil && input.Condition == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A saved search needs at least one filter"})
+		return false
+	}
+	if input.Search != "" && utils.FTSQuery(input.Search) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search must contain at least one letter or number"})
+		return false
+	}
+	if input.MinPrice != nil && input.MaxPrice != nil && *input.MinPrice > *input.MaxPrice {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_price must be greater than or equal to min_price"})
+		return false
+	}
+	if input.Condition != "" && !slices.Contains(models.ListingConditions, input.Condition) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Condition must be one of " + strings.Join(models.ListingConditions, ", ")})
+		return false
+	}
+	if input.Alerts == "" {
+		input.Alerts = models.AlertInstant
+	}
+	if !slices.Contains(models.AlertFrequencies, input.Alerts) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Alerts must be instant, daily or off"})
+		return false
+	}
+
+	var category models.Category
+	if input.CategoryID != nil {
+		if result := database.DB.First(&category, *input.CategoryID); result.Error != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+			return false
+		}
+	}
+
+	if input.Name == "" {
+		input.Name = savedSearchName(input.Search, category.Name)
+	}
+
+	search.Name = input.Name
+	search.Search = input.Search
+	search.CategoryID = input.CategoryID
+	search.MinPrice = input.MinPrice
+	search.MaxPrice = input.MaxPrice
+	search.Condition = input.Condition
+	search.Alerts = input.Alerts
+	return true
+}
+
+// savedSearchName makes up a name for a saved search that wasn't given one.
+func savedSearchName(search, category string) string {
+	switch {
+	case search != "" && category != "":
+		return search + " in " + category
+	case search != "":
+		return search
+	case category != "":
+		return category
+	}
+	return "Saved search"
+}
+
+func loadSavedSearchParam(c *gin.Context) (models.SavedSearch, bool) {
+	var search models.SavedSearch
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID"})
+		return search, false
+	}
+
+	if result := database.DB.Where("user_id = ?", c.GetUint("userID")).First(&search, id); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		return search, false
+	}
+	return search, true
+}
+
+// matchSavedSearches records a newly published listing against every saved
+// search it satisfies and alerts the owners of instant searches. Daily
+// searches are left for the digest job.
+func matchSavedSearches(listing models.Listing) {
+	var searches []models.SavedSearch
+	if result := database.DB.
+		Where("user_id != ? AND alerts != ?", listing.SellerID, models.AlertOff).
+		Where("category_id IS NULL OR category_id = ?", listing.CategoryID).
+		Where("min_price IS NULL OR min_price <= ?", listing.Price).
+		Where("max_price IS NULL OR max_price >= ?", listing.Price).
+		Where("condition = '' OR condition = ?", listing.Condition).
+		Where("user_id NOT IN (?)", database.DB.Model(&models.UserBlock{}).Select("blocked_id").Where("blocker_id = ?", listing.SellerID)).
+		Find(&searches); result.Error != nil {
+		log.Printf("Failed to match saved searches for listing %d: %v", listing.ID, result.Error)
+		return
+	}
+
+	alerted := map[uint]bool{}
+	for _, search := range searches {
+		if search.Search != "" && !listingMatchesText(listing, search.Search) {
+			continue
+		}
+
+		match := models.SavedSearchMatch{SavedSearchID: search.ID, ListingID: listing.ID}
+		if search.Alerts == models.AlertInstant {
+			now := time.Now()
+			match.DigestedAt = &now
+		}
+		if result := database.DB.Create(&match); result.Error != nil {
+			continue
+		}
+
+		// One alert per user even if several of their searches match
+		if search.Alerts != models.AlertInstant || alerted[search.UserID] {
+			continue
+		}
+		alerted[search.UserID] = true
+		notify.Send(search.UserID, models.NotificationSavedSearch, "New Match: "+search.Name,
+			fmt.Sprintf("%s ($%.2f) matches your saved search", listing.Title, listing.Price),
+			notify.ListingLink(listing.ID))
+	}
+}
+
+// listingMatchesText reports whether the listing's title or description
+// matches search the same way GetListings would find it.
+func listingMatchesText(listing models.Listing, search string) bool {
+	if !database.FTSEnabled {
+		search = strings.ToLower(search)
+		return strings.Contains(strings.ToLower(listing.Title), search) ||
+			strings.Contains(strings.ToLower(listing.Description), search)
+	}
+
+	var count int64
+	database.DB.Raw("SELECT COUNT(*) FROM listings_fts WHERE rowid = ? AND listings_fts MATCH ?",
+		listing.ID, utils.FTSQuery(search)).Scan(&count)
+	return count > 0
+}
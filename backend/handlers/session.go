@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/realtime"
+	"uf-marketplace/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"`
+}
+
+// startSession records a new signed-in device for the user and responds
+// with its first access/refresh token pair.
+func startSession(c *gin.Context, status int, user models.User) {
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	now := time.Now()
+	userAgent := c.Request.UserAgent()
+	session := models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		Device:           describeDevice(userAgent),
+		UserAgent:        userAgent,
+		IPAddress:        c.ClientIP(),
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(utils.RefreshTokenTTL),
+	}
+	if result := database.DB.Create(&session); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating session"})
+		return
+	}
+
+	token, err := utils.GenerateToken(user.ID, user.Email, session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	c.JSON(status, AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+		User:         user.ToResponse(),
+	})
+}
+
+// RefreshToken trades a refresh token for a new access token and rotates the
+// refresh token. Presenting a token that was already rotated out means it
+// leaked, so the whole session is revoked.
+func RefreshToken(c *gin.Context) {
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token is required"})
+		return
+	}
+
+	hash := utils.HashToken(input.RefreshToken)
+
+	var session models.Session
+	if result := database.DB.Where("refresh_token_hash = ?", hash).First(&session); result.Error != nil {
+		if result := database.DB.Where("previous_token_hash = ?", hash).First(&session); result.Error == nil {
+			revokeSession(&session)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used; please sign in again"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	if !session.IsActive() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has expired; please sign in again"})
+		return
+	}
+
+	var user models.User
+	if result := database.DB.First(&user, session.UserID); result.Error != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	if message := accountRestriction(&user); message != "" {
+		revokeSession(&session)
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+		return
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	// Only rotate if nobody else rotated this token in the meantime
+	now := time.Now()
+	result := database.DB.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ?", session.ID, hash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  utils.HashToken(refreshToken),
+			"previous_token_hash": hash,
+			"user_agent":          c.Request.UserAgent(),
+			"ip_address":          c.ClientIP(),
+			"last_used_at":        now,
+			"expires_at":          now.Add(utils.RefreshTokenTTL),
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error refreshing session"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	token, err := utils.GenerateToken(user.ID, user.Email, session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+		User:         user.ToResponse(),
+	})
+}
+
+// Logout revokes the current session, or every session of the user when
+// "all" is set. The session is taken from the access token when there is a
+// valid one, otherwise from the refresh token so an expired client can
+// still sign out.
+func Logout(c *gin.Context) {
+	var input LogoutInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+	if all, err := strconv.ParseBool(c.Query("all")); err == nil {
+		input.All = input.All || all
+	}
+
+	var session models.Session
+	var found bool
+	if sessionID := c.GetUint("sessionID"); sessionID != 0 {
+		found = database.DB.First(&session, sessionID).Error == nil
+	} else if input.RefreshToken != "" {
+		found = database.DB.Where("refresh_token_hash = ?", utils.HashToken(input.RefreshToken)).
+			First(&session).Error == nil
+	}
+	// A refresh token from a session that was already signed out or revoked
+	// mustn't be able to sign the user's other devices out
+	if !found || !session.IsActive() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not signed in"})
+		return
+	}
+
+	if input.All {
+		count := revokeUserSessions(session.UserID, 0)
+		c.JSON(http.StatusOK, gin.H{"message": "Signed out of all devices", "revoked": count})
+		return
+	}
+
+	revokeSession(&session)
+	c.JSON(http.StatusOK, gin.H{"message": "Signed out", "revoked": 1})
+}
+
+// GetSessions lists the devices the current user is signed in on.
+func GetSessions(c *gin.Context) {
+	userID := c.GetUint("userID")
+	sessionID := c.GetUint("sessionID")
+
+	var sessions []models.Session
+	if result := database.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").Find(&sessions); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sessions"})
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == sessionID
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession signs one of the current user's devices out.
+func RevokeSession(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	var session models.Session
+	if result := database.DB.Where("user_id = ?", userID).First(&session, id); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	revokeSession(&session)
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// streamTicketTTL is how long a stream ticket can wait to be used. Clients
+// ask for one right before opening the stream.
+const streamTicketTTL = 30 * time.Second
+
+// CreateStreamTicket issues a single-use ticket for opening the chat
+// WebSocket or the notification stream as the current session, passed as
+// ?ticket= since browsers can't set headers on those requests.
+func CreateStreamTicket(c *gin.Context) {
+	ticket, err := utils.RandomString(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating stream ticket"})
+		return
+	}
+
+	record := models.StreamTicket{
+		TicketHash: utils.HashToken(ticket),
+		UserID:     c.GetUint("userID"),
+		SessionID:  c.GetUint("sessionID"),
+		ExpiresAt:  time.Now().Add(streamTicketTTL),
+	}
+	if result := database.DB.Create(&record); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating stream ticket"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"ticket": ticket, "expires_at": record.ExpiresAt})
+}
+
+func revokeSession(session *models.Session) {
+	if session.RevokedAt != nil {
+		return
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	database.DB.Model(session).Update("revoked_at", now)
+	closeSessionStreams(session.UserID, session.ID)
+}
+
+// revokeUserSessions revokes all of a user's active sessions except keepID
+// and returns how many were revoked.
+func revokeUserSessions(userID, keepID uint) int64 {
+	var sessionIDs []uint
+	database.DB.Model(&models.Session{}).
+		Where("user_id = ? AND id != ? AND revoked_at IS NULL", userID, keepID).
+		Pluck("id", &sessionIDs)
+	if len(sessionIDs) == 0 {
+		return 0
+	}
+
+	result := database.DB.Model(&models.Session{}).
+		Where("id IN ? AND revoked_at IS NULL", sessionIDs).
+		Update("revoked_at", time.Now())
+	for _, sessionID := range sessionIDs {
+		closeSessionStreams(userID, sessionID)
+	}
+	return result.RowsAffected
+}
+
+// closeSessionStreams disconnects the session's chat WebSockets and
+// notification streams, which are only authenticated when they open.
+func closeSessionStreams(userID, sessionID uint) {
+	realtime.ChatHub.CloseSession(userID, sessionID)
+	realtime.NotificationBroker.CloseSession(userID, sessionID)
+}
+
+// describeDevice turns a user agent into a short label like
+// "Chrome on macOS" for the sessions list.
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		platform = "iOS"
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os"):
+		platform = "macOS"
+	case strings.Contains(ua, "cros"):
+		platform = "ChromeOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
@@ -0,0 +1,419 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/mailer"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+	"uf-marketplace/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	twoFactorPurpose  = "two-factor-login"
+	twoFactorTTL      = 5 * time.Minute
+	twoFactorIssuer   = "UF Market"
+	recoveryCodeCount = 10
+)
+
+type TwoFactorSetupInput struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type TwoFactorCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorInput struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginInput struct {
+	TwoFactorToken string `json:"two_factor_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TwoFactorChallenge is what Login answers with instead of an AuthResponse
+// when the account has two-factor sign-in on.
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	TwoFactorToken    string `json:"two_factor_token"`
+	ExpiresIn         int    `json:"expires_in"`
+}
+
+// startTwoFactor answers a correct password with a short-lived token for
+// the second step. Only the newest token works.
+func startTwoFactor(c *gin.Context, user *models.User) {
+	nonce, err := utils.RandomString(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+	if result := database.DB.Model(user).Update("two_factor_nonce", nonce); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error signing in"})
+		return
+	}
+
+	recordLoginAttempt(c, user.Email, user.ID, models.LoginTwoFactor)
+
+	c.JSON(http.StatusOK, TwoFactorChallenge{
+		TwoFactorRequired: true,
+		TwoFactorToken:    utils.GenerateSignedToken(twoFactorPurpose, user.ID, nonce, twoFactorTTL),
+		ExpiresIn:         int(twoFactorTTL.Seconds()),
+	})
+}
+
+// VerifyTwoFactorLogin finishes a two-step sign-in with an authenticator or
+// recovery code.
+func VerifyTwoFactorLogin(c *gin.Context) {
+	var input TwoFactorLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token and code are required"})
+		return
+	}
+
+	userID, nonce, err := utils.ParseSignedToken(twoFactorPurpose, input.TwoFactorToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "This sign-in has expired; please sign in again"})
+		return
+	}
+
+	var user models.User
+	if result := database.DB.First(&user, userID); result.Error != nil ||
+		!user.TwoFactorEnabled() || user.TwoFactorNonce != nonce {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "This sign-in has expired; please sign in again"})
+		return
+	}
+
+	if loginThrottled(c, user.Email, user.ID) {
+		return
+	}
+
+	if message := accountRestriction(&user); message != "" {
+		recordLoginAttempt(c, user.Email, user.ID, models.LoginRestricted)
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+		return
+	}
+
+	recovery, ok := useSecondFactor(&user, input.Code)
+	if !ok {
+		recordLoginFailure(c, &user, models.LoginBadCode)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	// Retire the token so it can't finish a second sign-in
+	result := database.DB.Model(&user).Where("two_factor_nonce = ?", nonce).Update("two_factor_nonce", "")
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "This sign-in has expired; please sign in again"})
+		return
+	}
+
+	recordLoginAttempt(c, user.Email, user.ID, models.LoginSuccess)
+	clearLoginFailures(user.Email)
+
+	if recovery {
+		remaining := remainingRecoveryCodes(user.ID)
+		notify.Send(user.ID, models.NotificationSecurity, "Recovery Code Used",
+			fmt.Sprintf("A recovery code was used to sign in. You have %d left; generate new ones in settings if you're running low.", remaining),
+			"/settings")
+	}
+
+	startSession(c, http.StatusOK, user)
+}
+
+// GetTwoFactorStatus reports whether the current user has two-factor
+// sign-in on and how many recovery codes are left.
+func GetTwoFactorStatus(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.TwoFactorEnabled(),
+		"enabled_at":               user.TOTPEnabledAt,
+		"recovery_codes_remaining": remainingRecoveryCodes(user.ID),
+	})
+}
+
+// SetupTwoFactor creates a new secret for the current user to add to an
+// authenticator app. Nothing changes at sign-in until EnableTwoFactor
+// confirms a code from it.
+func SetupTwoFactor(c *gin.Context) {
+	var input TwoFactorSetupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is required"})
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TwoFactorEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already on"})
+		return
+	}
+	if !utils.CheckPassword(input.Password, user.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is incorrect"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating secret"})
+		return
+	}
+	if result := database.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_secret":       secret,
+		"totp_last_counter": 0,
+	}); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error setting up two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(secret, twoFactorIssuer, user.Email),
+		"digits":           utils.TOTPDigits,
+		"period":           utils.TOTPPeriod,
+	})
+}
+
+// EnableTwoFactor turns two-factor sign-in on once the user proves their
+// app produces the right codes, and hands out the recovery codes. This is
+// the only time the codes are shown.
+func EnableTwoFactor(c *gin.Context) {
+	var input TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is required"})
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TwoFactorEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already on"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set up two-factor authentication first"})
+		return
+	}
+	if !useTOTP(&user, input.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("totp_enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = issueRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error enabling two-factor authentication"})
+		return
+	}
+
+	notifyTwoFactorChange(&user, true)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication is on",
+		"recovery_codes": codes,
+		"user":           user.ToResponse(),
+	})
+}
+
+// DisableTwoFactor turns two-factor sign-in off. It takes both the
+// password and a current code so a stolen session alone can't do it.
+func DisableTwoFactor(c *gin.Context) {
+	var input DisableTwoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password and code are required"})
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.TwoFactorEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not on"})
+		return
+	}
+	if !utils.CheckPassword(input.Password, user.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is incorrect"})
+		return
+	}
+	if _, ok := useSecondFactor(&user, input.Code); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_secret":       "",
+			"totp_enabled_at":   nil,
+			"totp_last_counter": 0,
+			"two_factor_nonce":  "",
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error disabling two-factor authentication"})
+		return
+	}
+
+	notifyTwoFactorChange(&user, false)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication is off", "user": user.ToResponse()})
+}
+
+// RegenerateRecoveryCodes replaces all of the current user's recovery
+// codes with a new set.
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var input TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is required"})
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.TwoFactorEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not on"})
+		return
+	}
+	if !useTOTP(&user, input.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = issueRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating recovery codes"})
+		return
+	}
+
+	notify.Send(user.ID, models.NotificationSecurity, "New Recovery Codes",
+		"New two-factor recovery codes were generated and the old ones no longer work.", "/settings")
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func loadCurrentUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	if result := database.DB.First(&user, c.GetUint("userID")); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+	return user, true
+}
+
+// useTOTP checks an authenticator code and claims its time step, so each
+// code works once.
+func useTOTP(user *models.User, code string) bool {
+	counter, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return false
+	}
+
+	result := database.DB.Model(user).
+		Where("totp_last_counter < ?", counter).
+		Update("totp_last_counter", counter)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+func useRecoveryCode(userID uint, code string) bool {
+	result := database.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, utils.HashToken(utils.NormalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// useSecondFactor accepts either an authenticator code or an unused
+// recovery code, and reports whether it was a recovery code.
+func useSecondFactor(user *models.User, code string) (recovery bool, ok bool) {
+	if useTOTP(user, code) {
+		return false, true
+	}
+	if useRecoveryCode(user.ID, code) {
+		return true, true
+	}
+	return false, false
+}
+
+// issueRecoveryCodes replaces the user's recovery codes and returns the new
+// ones in plain text.
+func issueRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	records := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(utils.NormalizeRecoveryCode(code))}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func remainingRecoveryCodes(userID uint) int64 {
+	var count int64
+	database.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count)
+	return count
+}
+
+func notifyTwoFactorChange(user *models.User, enabled bool) {
+	title, state := "Two-Factor Authentication On", "on"
+	if !enabled {
+		title, state = "Two-Factor Authentication Off", "off"
+	}
+
+	notify.Send(user.ID, models.NotificationSecurity, title,
+		"Two-factor authentication was turned "+state+" for your account. If this wasn't you, reset your password right away.",
+		"/settings")
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Two-factor authentication was turned " + state,
+		Body: "Hi " + user.FirstName + ",\n\n" +
+			"Two-factor authentication was just turned " + state + " for your UF Market account.\n\n" +
+			"If you didn't do this, reset your password right away:\n\n" +
+			appURL("/forgot-password") + "\n",
+	})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/utils"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func useTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+}
+
+func TestUseTOTPRefusesReplay(t *testing.T) {
+	useTestDB(t)
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{Email: "replay@ufl.edu", FirstName: "A", LastName: "B", TOTPSecret: secret}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	step := utils.TOTPCounter(time.Now())
+	previous, _ := utils.TOTPCode(secret, step-1)
+	current, _ := utils.TOTPCode(secret, step)
+
+	if !useTOTP(&user, current) {
+		t.Fatal("fresh code was rejected")
+	}
+	if useTOTP(&user, current) {
+		t.Error("the same code was accepted twice")
+	}
+	if useTOTP(&user, previous) {
+		t.Error("a code from an earlier step was accepted after a later one was used")
+	}
+
+	var stored models.User
+	database.DB.First(&stored, user.ID)
+	if stored.TOTPLastCounter != step {
+		t.Errorf("totp_last_counter = %d, want %d", stored.TOTPLastCounter, step)
+	}
+}
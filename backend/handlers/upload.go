@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/imaging"
+	"uf-marketplace/models"
+	"uf-marketplace/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func UploadImage(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	// Cap the body before the multipart form is parsed, leaving room for
+	// the form's own overhead
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, imaging.MaxUploadBytes+1<<20)
+
+	file, err := c.FormFile("image")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image must be 10MB or smaller"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No image provided"})
+		return
+	}
+
+	if file.Size > imaging.MaxUploadBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image must be 10MB or smaller"})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error reading image"})
+		return
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, imaging.MaxUploadBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error reading image"})
+		return
+	}
+
+	processed, err := imaging.Process(data)
+	switch {
+	case errors.Is(err, imaging.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image must be 10MB or smaller"})
+		return
+	case errors.Is(err, imaging.ErrUnsupportedType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File must be a JPEG, PNG, GIF or WebP image"})
+		return
+	case errors.Is(err, imaging.ErrTooManyPixels):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image dimensions are too large"})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image could not be processed"})
+		return
+	}
+
+	// Save every variant under a shared unique prefix
+	prefix := strconv.FormatInt(time.Now().UnixNano(), 10)
+	urls := make(map[string]string)
+	var size int64
+	for _, encoded := range processed.Variants {
+		key := fmt.Sprintf("%s_%s.jpg", prefix, encoded.Variant.Name)
+		if err := storage.Default.Put(c.Request.Context(), key, encoded.Data, "image/jpeg"); err != nil {
+			log.Printf("Failed to store upload %s: %v", key, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving image"})
+			return
+		}
+		urls[encoded.Variant.Name] = storage.URL(key)
+		size += int64(len(encoded.Data))
+	}
+
+	full := processed.Variant("full")
+	upload := models.Upload{
+		UserID:       userID,
+		URL:          urls["full"],
+		MediumURL:    urls["medium"],
+		ThumbnailURL: urls["thumbnail"],
+		Width:        full.Width,
+		Height:       full.Height,
+		MimeType:     processed.MimeType,
+		Size:         size,
+	}
+	if result := database.DB.Create(&upload); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving image"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":           upload.URL,
+		"filename":      path.Base(upload.URL),
+		"medium_url":    upload.MediumURL,
+		"thumbnail_url": upload.ThumbnailURL,
+		"width":         upload.Width,
+		"height":        upload.Height,
+	})
+}
+
+// newListingImage builds a ListingImage for an image URL, copying the
+// variant URLs and dimensions recorded when it was uploaded. Images that
+// predate the upload pipeline fall back to the original for every size.
+func newListingImage(listingID uint, imageURL string, isPrimary bool) models.ListingImage {
+	image := models.ListingImage{
+		ListingID:    listingID,
+		ImageURL:     imageURL,
+		IsPrimary:    isPrimary,
+		MediumURL:    imageURL,
+		ThumbnailURL: imageURL,
+	}
+
+	var upload models.Upload
+	if result := database.DB.Where("url = ?", imageURL).First(&upload); result.Error == nil {
+		image.MediumURL = upload.MediumURL
+		image.ThumbnailURL = upload.ThumbnailURL
+		image.Width = upload.Width
+		image.Height = upload.Height
+		return image
+	}
+
+	// Keep the variants of an image the listing already has
+	var existing models.ListingImage
+	if result := database.DB.Where("listing_id = ? AND image_url = ?", listingID, imageURL).First(&existing); result.Error == nil {
+		image.MediumURL = existing.MediumURL
+		image.ThumbnailURL = existing.ThumbnailURL
+		image.Width = existing.Width
+		image.Height = existing.Height
+	}
+
+	return image
+}
+
+// ServeUpload answers /uploads/* through the configured storage backend:
+// local files are served directly, S3 redirects to a presigned URL.
+func ServeUpload(c *gin.Context) {
+	key, err := storage.CleanKey(c.Param("key"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	storage.Default.Serve(c.Writer, c.Request, key)
+}
+
+// isOwnUpload reports whether url points at an image this user uploaded.
+func isOwnUpload(userID uint, url string) bool {
+	if !strings.HasPrefix(url, storage.URLPrefix) {
+		return false
+	}
+
+	var count int64
+	database.DB.Model(&models.Upload{}).
+		Where("user_id = ? AND (url = ? OR medium_url = ? OR thumbnail_url = ?)", userID, url, url, url).
+		Count(&count)
+	return count > 0
+}
+
+// isListingImage reports whether url is already one of this listing's
+// images. Listings from before uploads were tracked have images with no
+// Upload row, and those can still be kept when the listing is edited.
+func isListingImage(listingID uint, url string) bool {
+	if listingID == 0 {
+		return false
+	}
+
+	var count int64
+	database.DB.Model(&models.ListingImage{}).
+		Where("listing_id = ? AND image_url = ?", listingID, url).
+		Count(&count)
+	return count > 0
+}
+
+// checkListingImages responds with 400 and returns false unless every
+// image is one the user uploaded through /api/upload or is already on the
+// listing being edited (0 for a new listing).
+func checkListingImages(c *gin.Context, userID, listingID uint, images []string) bool {
+	for _, imageURL := range images {
+		if !isOwnUpload(userID, imageURL) && !isListingImage(listingID, imageURL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Listing images must be uploaded first", "image": imageURL})
+			return false
+		}
+	}
+	return true
+}
@@ -39,7 +39,10 @@ func GetUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user.ToResponse())
+	c.JSON(http.StatusOK, UserProfileResponse{
+		UserResponse:  user.ToResponse(),
+		RatingSummary: ratingSummary(user.ID),
+	})
 }
 
 func UpdateUser(c *gin.Context) {
@@ -79,6 +82,12 @@ func UpdateUser(c *gin.Context) {
 		user.Bio = input.Bio
 	}
 	if input.ProfileImage != "" {
+		// Profile images must come through /api/upload like listing images,
+		// though resending the current one is fine
+		if input.ProfileImage != user.ProfileImage && !isOwnUpload(userID, input.ProfileImage) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Profile image must be uploaded first"})
+			return
+		}
 		user.ProfileImage = input.ProfileImage
 	}
 
@@ -98,12 +107,18 @@ func GetUserListings(c *gin.Context) {
 		return
 	}
 
+	// Blocked viewers just see an empty shop
+	if isBlockedBy(uint(id), c.GetUint("userID")) {
+		c.JSON(http.StatusOK, []models.Listing{})
+		return
+	}
+
 	var listings []models.Listing
 	result := database.DB.
 		Preload("Images").
 		Preload("Category").
 		Preload("Seller").
-		Where("seller_id = ?", id).
+		Where("seller_id = ? AND status != ? AND hidden_at IS NULL", id, models.StatusRemoved).
 		Order("created_at DESC").
 		Find(&listings)
 
@@ -175,5 +190,8 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// Sign out every other device that knew the old password
+	revokeUserSessions(user.ID, c.GetUint("sessionID"))
+
 	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
 }
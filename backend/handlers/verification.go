@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/mailer"
+	"uf-marketplace/models"
+	"uf-marketplace/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	verificationPurpose  = "email-verification"
+	verificationTTL      = 48 * time.Hour
+	verificationCooldown = time.Minute
+)
+
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmail confirms the address behind an emailed verification link.
+func VerifyEmail(c *gin.Context) {
+	var input VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification token is required"})
+		return
+	}
+
+	userID, nonce, err := utils.ParseSignedToken(verificationPurpose, input.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This verification link is invalid or has expired"})
+		return
+	}
+
+	var user models.User
+	if result := database.DB.First(&user, userID); result.Error != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This verification link is invalid or has expired"})
+		return
+	}
+
+	if user.IsVerified() {
+		c.JSON(http.StatusOK, gin.H{"message": "Email already verified", "user": user.ToResponse()})
+		return
+	}
+
+	// The nonce is cleared on use and replaced on resend, so each link
+	// works once and only the newest one works at all
+	now := time.Now()
+	result := database.DB.Model(&user).
+		Where("verification_nonce = ?", nonce).
+		Updates(map[string]interface{}{"email_verified_at": now, "verification_nonce": ""})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verifying email"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This verification link is invalid or has expired"})
+		return
+	}
+
+	user.EmailVerifiedAt = &now
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified", "user": user.ToResponse()})
+}
+
+// ResendVerification emails the current user a fresh verification link.
+func ResendVerification(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var user models.User
+	if result := database.DB.First(&user, userID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.IsVerified() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	if user.VerificationSentAt != nil && time.Since(*user.VerificationSentAt) < verificationCooldown {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait a minute before requesting another email"})
+		return
+	}
+
+	if err := sendVerificationEmail(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error sending verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// sendVerificationEmail issues a new single-use link for user, invalidating
+// any earlier one, and mails it in the background.
+func sendVerificationEmail(user *models.User) error {
+	nonce, err := utils.RandomString(16)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(user).Updates(map[string]interface{}{
+		"verification_nonce":   nonce,
+		"verification_sent_at": now,
+	}).Error; err != nil {
+		return err
+	}
+
+	token := utils.GenerateSignedToken(verificationPurpose, user.ID, nonce, verificationTTL)
+	link := appURL("/verify-email?token=" + url.QueryEscape(token))
+
+	sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your UF Market account",
+		Body: "Hi " + user.FirstName + ",\n\n" +
+			"Confirm your @ufl.edu address to start listing items and messaging sellers:\n\n" +
+			link + "\n\n" +
+			"The link expires in 48 hours. If you didn't sign up for UF Market, you can ignore this email.\n",
+	})
+	return nil
+}
+
+// sendMail delivers msg off the request path so a slow mail server doesn't
+// hold up the response.
+func sendMail(msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := mailer.Default.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// appURL links to a page of the frontend, taken from APP_URL.
+func appURL(path string) string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "http://localhost:4200"
+	}
+	return strings.TrimSuffix(base, "/") + path
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"uf-marketplace/database"
+	"uf-marketplace/middleware"
+	"uf-marketplace/models"
+	"uf-marketplace/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Browsers don't apply CORS to WebSockets, so check the origin here.
+	// Clients other than browsers don't send one.
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || middleware.AllowedOrigin(origin)
+	},
+}
+
+func ChatWebSocket(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already written the error response
+		return
+	}
+
+	realtime.ChatHub.Serve(userID, c.GetUint("sessionID"), conn, handleChatFrame)
+}
+
+// handleChatFrame reacts to frames sent by the browser over the socket.
+func handleChatFrame(client *realtime.Client, incoming realtime.Incoming) {
+	var chat models.Chat
+	if result := database.DB.First(&chat, incoming.ChatID); result.Error != nil {
+		return
+	}
+	if chat.BuyerID != client.UserID && chat.SellerID != client.UserID {
+		return
+	}
+
+	switch incoming.Type {
+	case realtime.EventTyping:
+		publishChatEvent(chat, realtime.EventTyping, gin.H{"user_id": client.UserID})
+	case realtime.EventRead:
+		markChatRead(chat, client.UserID)
+	}
+}
+
+// publishChatEvent pushes an event to both participants of a chat.
+func publishChatEvent(chat models.Chat, eventType string, data interface{}) {
+	realtime.ChatHub.Publish([]uint{chat.BuyerID, chat.SellerID}, realtime.Event{
+		Type:   eventType,
+		ChatID: chat.ID,
+		Data:   data,
+	})
+}
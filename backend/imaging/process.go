@@ -0,0 +1,150 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	// Registered decoders for the formats we accept
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/gabriel-vasile/mimetype"
+	"golang.org/x/image/draw"
+)
+
+const (
+	// MaxUploadBytes caps the size of a single uploaded file
+	MaxUploadBytes = 10 << 20
+	// MaxPixels guards against decompression bombs: a tiny file that
+	// decodes to an enormous bitmap
+	MaxPixels = 40_000_000
+
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedType = errors.New("file is not a supported image type")
+	ErrTooLarge        = errors.New("image file is too large")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+	ErrCorrupt         = errors.New("image could not be decoded")
+)
+
+// AllowedTypes are the MIME types accepted for upload, detected from the
+// file contents rather than the name or Content-Type header.
+var AllowedTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// Variant is one re-encoded size of an uploaded image.
+type Variant struct {
+	Name    string
+	MaxEdge int
+}
+
+// Variants are generated for every upload, smallest first. Images are never
+// upscaled, so a small original yields variants of its own size.
+var Variants = []Variant{
+	{Name: "thumbnail", MaxEdge: 320},
+	{Name: "medium", MaxEdge: 800},
+	{Name: "full", MaxEdge: 1920},
+}
+
+// Encoded is a single re-encoded JPEG variant.
+type Encoded struct {
+	Variant Variant
+	Data    []byte
+	Width   int
+	Height  int
+}
+
+// Result is the outcome of processing an upload.
+type Result struct {
+	MimeType string
+	Variants []Encoded
+}
+
+// Variant returns the encoded variant with the given name.
+func (r *Result) Variant(name string) Encoded {
+	for _, encoded := range r.Variants {
+		if encoded.Variant.Name == name {
+			return encoded
+		}
+	}
+	return Encoded{}
+}
+
+// Process validates an uploaded image and produces every variant. Each one
+// is decoded and re-encoded from scratch, so EXIF, GPS and any other
+// metadata in the original never reaches disk. The EXIF orientation is
+// applied to the pixels first so photos taken sideways display upright.
+func Process(data []byte) (*Result, error) {
+	if len(data) > MaxUploadBytes {
+		return nil, ErrTooLarge
+	}
+
+	mime := mimetype.Detect(data)
+	if !mimetype.EqualsAny(mime.String(), AllowedTypes...) {
+		return nil, ErrUnsupportedType
+	}
+
+	// Check dimensions from the header before allocating the bitmap
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+
+	if mime.Is("image/jpeg") {
+		src = applyOrientation(src, jpegOrientation(data))
+	}
+
+	// Work down from the largest variant, scaling each smaller one from the
+	// previous result instead of the (possibly huge) original
+	result := &Result{MimeType: mime.String(), Variants: make([]Encoded, len(Variants))}
+	for i := len(Variants) - 1; i >= 0; i-- {
+		encoded, scaled, err := encodeVariant(src, Variants[i])
+		if err != nil {
+			return nil, err
+		}
+		result.Variants[i] = encoded
+		src = scaled
+	}
+
+	return result, nil
+}
+
+func encodeVariant(src image.Image, variant Variant) (Encoded, image.Image, error) {
+	width, height := fit(src.Bounds().Dx(), src.Bounds().Dy(), variant.MaxEdge)
+
+	// JPEG has no alpha channel, so flatten transparent areas onto white
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return Encoded{}, nil, err
+	}
+
+	return Encoded{Variant: variant, Data: buf.Bytes(), Width: width, Height: height}, dst, nil
+}
+
+// fit scales width and height down so the longer edge is at most maxEdge.
+func fit(width, height, maxEdge int) (int, int) {
+	longest := max(width, height)
+	if longest <= maxEdge {
+		return width, height
+	}
+	scale := float64(maxEdge) / float64(longest)
+	return max(1, int(float64(width)*scale+0.5)), max(1, int(float64(height)*scale+0.5))
+}
@@ -0,0 +1,162 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// testJPEG encodes a width x height JPEG, inserting an EXIF block with the
+// given orientation unless it is 0.
+func testJPEG(t *testing.T, width, height int, order binary.ByteOrder, orientation int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if orientation == 0 {
+		return buf.Bytes()
+	}
+
+	// TIFF header, then IFD0 holding just the orientation tag
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], exifOrientationTag)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], uint16(orientation))
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no EXIF", testJPEG(t, 4, 4, nil, 0), 1},
+		{"little endian", testJPEG(t, 4, 4, binary.LittleEndian, 6), 6},
+		{"big endian", testJPEG(t, 4, 4, binary.BigEndian, 3), 3},
+		{"out of range", testJPEG(t, 4, 4, binary.LittleEndian, 9), 1},
+		{"not a JPEG", []byte("GIF89a...."), 1},
+		{"truncated segment", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 'E'}, 1},
+	}
+
+	for _, tt := range tests {
+		if got := jpegOrientation(tt.data); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// 3 wide, 2 tall, with the top-left pixel marked
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	marked := color.NRGBA{255, 0, 0, 255}
+	src.Set(0, 0, marked)
+
+	tests := []struct {
+		orientation   int
+		width, height int
+		x, y          int // where the top-left pixel ends up
+	}{
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+	}
+
+	for _, tt := range tests {
+		dst := applyOrientation(src, tt.orientation)
+		bounds := dst.Bounds()
+		if bounds.Dx() != tt.width || bounds.Dy() != tt.height {
+			t.Errorf("orientation %d: got %dx%d, want %dx%d", tt.orientation, bounds.Dx(), bounds.Dy(), tt.width, tt.height)
+			continue
+		}
+		if got := color.NRGBAModel.Convert(dst.At(tt.x, tt.y)); got != marked {
+			t.Errorf("orientation %d: top-left pixel not at (%d,%d)", tt.orientation, tt.x, tt.y)
+		}
+	}
+}
+
+func TestProcessAppliesOrientationAndStripsEXIF(t *testing.T) {
+	// Rotated 90 degrees: stored landscape, displayed portrait
+	result, err := Process(testJPEG(t, 40, 20, binary.LittleEndian, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if full := result.Variant("full"); full.Width != 20 || full.Height != 40 {
+		t.Errorf("full variant is %dx%d, want 20x40", full.Width, full.Height)
+	}
+	for _, encoded := range result.Variants {
+		if bytes.Contains(encoded.Data, []byte("Exif")) || jpegOrientation(encoded.Data) != 1 {
+			t.Errorf("%s variant still carries EXIF", encoded.Variant.Name)
+		}
+	}
+}
+
+// pngHeader is a PNG signature and IHDR chunk claiming the given size,
+// with no image data after it.
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	ihdr[12] = 8 // bit depth
+	ihdr[13] = 2 // truecolor
+
+	data := []byte("\x89PNG\r\n\x1a\n")
+	data = binary.BigEndian.AppendUint32(data, 13)
+	data = append(data, ihdr...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(ihdr))
+}
+
+func TestProcessRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"plain text", []byte("just some text, not an image"), ErrUnsupportedType},
+		{"PDF", []byte("%PDF-1.4\n%âãÏÓ\n1 0 obj\n"), ErrUnsupportedType},
+		{"pixel bomb", pngHeader(50_000, 50_000), ErrTooManyPixels},
+		{"too large", make([]byte, MaxUploadBytes+1), ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		if _, err := Process(tt.data); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
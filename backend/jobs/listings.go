@@ -0,0 +1,129 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+
+	"gorm.io/gorm"
+)
+
+// Listing expiry settings. LISTING_EXPIRY_INTERVAL and
+// LISTING_EXPIRY_WARNING take Go durations (e.g. "1h", "72h").
+var (
+	ListingExpiryInterval = time.Hour
+	ListingExpiryWarning  = 3 * 24 * time.Hour
+)
+
+// expiringStatuses are the statuses a listing can expire from. Pending
+// listings have a sale agreed, so they're left alone.
+var expiringStatuses = []models.ListingStatus{models.StatusActive, models.StatusReserved}
+
+// RemindExpiringListings notifies sellers whose listings expire within the
+// warning period, once per listing lifetime, and returns how many were
+// reminded.
+func RemindExpiringListings() (int, error) {
+	var listings []models.Listing
+	if err := database.DB.
+		Where("status IN ? AND hidden_at IS NULL AND expiry_reminded_at IS NULL", expiringStatuses).
+		Where("expires_at > ? AND expires_at <= ?", time.Now(), time.Now().Add(ListingExpiryWarning)).
+		Find(&listings).Error; err != nil {
+		return 0, err
+	}
+
+	for _, listing := range listings {
+		if err := database.DB.Model(&listing).Update("expiry_reminded_at", time.Now()).Error; err != nil {
+			return 0, err
+		}
+		notify.Send(listing.SellerID, models.NotificationListingExpiring, "Listing Expiring Soon",
+			"Your listing \""+listing.Title+"\" expires on "+listing.ExpiresAt.Format("Jan 2")+". Renew it to keep it in search results.",
+			notify.ListingLink(listing.ID))
+	}
+	return len(listings), nil
+}
+
+// ExpireListings makes listings past their expiry date inactive, recording
+// the change in each listing's history, and returns how many expired.
+func ExpireListings() (int, error) {
+	var listings []models.Listing
+	if err := database.DB.Where("status IN ? AND expires_at <= ?", expiringStatuses, time.Now()).
+		Find(&listings).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, listing := range listings {
+		if !models.CanTransition(listing.Status, models.StatusInactive, models.ActorSystem) {
+			continue
+		}
+
+		changed := false
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			// Skip it if the seller renewed or changed it since we looked
+			result := tx.Model(&models.Listing{}).
+				Where("id = ? AND status = ? AND expires_at <= ?", listing.ID, listing.Status, time.Now()).
+				Update("status", models.StatusInactive)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			changed = true
+			return tx.Create(&models.ListingStatusChange{
+				ListingID:  listing.ID,
+				FromStatus: listing.Status,
+				ToStatus:   models.StatusInactive,
+				Actor:      models.ActorSystem,
+				Reason:     "Expired",
+			}).Error
+		})
+		if err != nil {
+			return expired, err
+		}
+		if !changed {
+			continue
+		}
+
+		expired++
+		notify.Send(listing.SellerID, models.NotificationListingExpired, "Listing Expired",
+			"Your listing \""+listing.Title+"\" expired and was taken out of search. Renew it to put it back up.",
+			notify.ListingLink(listing.ID))
+	}
+	return expired, nil
+}
+
+// StartListingExpirer runs RemindExpiringListings and ExpireListings in the
+// background until ctx is done.
+func StartListingExpirer(ctx context.Context) {
+	if value, err := time.ParseDuration(os.Getenv("LISTING_EXPIRY_INTERVAL")); err == nil && value > 0 {
+		ListingExpiryInterval = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("LISTING_EXPIRY_WARNING")); err == nil && value >= 0 {
+		ListingExpiryWarning = value
+	}
+
+	go func() {
+		ticker := time.NewTicker(ListingExpiryInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := RemindExpiringListings(); err != nil {
+					log.Printf("Listing expiry reminders failed: %v", err)
+				}
+				count, err := ExpireListings()
+				if err != nil {
+					log.Printf("Listing expiry failed: %v", err)
+				}
+				if count > 0 {
+					log.Printf("Listing expiry: %d listings expired", count)
+				}
+			}
+		}
+	}()
+}
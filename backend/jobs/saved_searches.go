@@ -0,0 +1,123 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/notify"
+)
+
+// Saved search digest settings. Each daily saved search gets at most one
+// digest per SavedSearchDigestPeriod; pending matches are checked every
+// SavedSearchDigestInterval (SAVED_SEARCH_DIGEST_INTERVAL, e.g. "1h").
+var (
+	SavedSearchDigestInterval = time.Hour
+	SavedSearchDigestPeriod   = 24 * time.Hour
+)
+
+// SendSavedSearchDigests sends each user one notification summing up the
+// listings that matched their daily saved searches since the last digest,
+// and returns how many users were notified.
+func SendSavedSearchDigests() (int, error) {
+	now := time.Now()
+
+	var searches []models.SavedSearch
+	if err := database.DB.
+		Where("alerts = ? AND (last_digest_at IS NULL OR last_digest_at <= ?)", models.AlertDaily, now.Add(-SavedSearchDigestPeriod)).
+		Where("id IN (?)", database.DB.Model(&models.SavedSearchMatch{}).Select("saved_search_id").Where("digested_at IS NULL")).
+		Order("user_id, id").
+		Find(&searches).Error; err != nil {
+		return 0, err
+	}
+
+	type digest struct {
+		lines     []string
+		total     int64
+		listingID uint
+	}
+	digests := map[uint]*digest{}
+	var userIDs []uint
+
+	for _, search := range searches {
+		// Only listings that are still up are worth mentioning
+		var matches []models.SavedSearchMatch
+		if err := database.DB.Joins("JOIN listings ON listings.id = saved_search_matches.listing_id").
+			Where("saved_search_matches.saved_search_id = ? AND saved_search_matches.digested_at IS NULL", search.ID).
+			Where("listings.status = ? AND listings.hidden_at IS NULL AND listings.deleted_at IS NULL", models.StatusActive).
+			Find(&matches).Error; err != nil {
+			return 0, err
+		}
+
+		if err := database.DB.Model(&models.SavedSearchMatch{}).
+			Where("saved_search_id = ? AND digested_at IS NULL", search.ID).
+			Update("digested_at", now).Error; err != nil {
+			return 0, err
+		}
+		if err := database.DB.Model(&search).Update("last_digest_at", now).Error; err != nil {
+			return 0, err
+		}
+
+		if len(matches) == 0 {
+			continue
+		}
+		d := digests[search.UserID]
+		if d == nil {
+			d = &digest{}
+			digests[search.UserID] = d
+			userIDs = append(userIDs, search.UserID)
+		}
+		noun := "listings match"
+		if len(matches) == 1 {
+			noun = "listing matches"
+		}
+		d.lines = append(d.lines, fmt.Sprintf("%d new %s \"%s\"", len(matches), noun, search.Name))
+		d.total += int64(len(matches))
+		d.listingID = matches[0].ListingID
+	}
+
+	for _, userID := range userIDs {
+		d := digests[userID]
+		// Link straight to the listing when there's only one
+		link := "/dashboard"
+		if d.total == 1 {
+			link = notify.ListingLink(d.listingID)
+		}
+		notify.Send(userID, models.NotificationSavedSearch, "Your Saved Search Digest",
+			strings.Join(d.lines, "; "), link)
+	}
+	return len(userIDs), nil
+}
+
+// StartSavedSearchDigester runs SendSavedSearchDigests in the background
+// until ctx is done.
+func StartSavedSearchDigester(ctx context.Context) {
+	if value, err := time.ParseDuration(os.Getenv("SAVED_SEARCH_DIGEST_INTERVAL")); err == nil && value > 0 {
+		SavedSearchDigestInterval = value
+	}
+
+	go func() {
+		ticker := time.NewTicker(SavedSearchDigestInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				count, err := SendSavedSearchDigests()
+				if err != nil {
+					log.Printf("Saved search digest failed: %v", err)
+					continue
+				}
+				if count > 0 {
+					log.Printf("Saved search digest: notified %d users", count)
+				}
+			}
+		}
+	}()
+}
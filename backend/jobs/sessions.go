@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+)
+
+// Revoked and expired sessions are kept this long so users can still see
+// recent sign-outs, then purged.
+var (
+	SessionPurgeInterval  = 24 * time.Hour
+	SessionRetentionAfter = 30 * 24 * time.Hour
+	// Login attempts are kept longer so they can be audited
+	LoginAttemptRetention = 90 * 24 * time.Hour
+)
+
+// PurgeSessions deletes sessions that expired or were revoked longer ago
+// than the retention period and returns how many were removed.
+func PurgeSessions() (int64, error) {
+	cutoff := time.Now().Add(-SessionRetentionAfter)
+	result := database.DB.Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).
+		Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
+
+// PurgePasswordResets deletes password reset requests once they are past
+// both their expiry and the retention period.
+func PurgePasswordResets() (int64, error) {
+	result := database.DB.Where("expires_at < ?", time.Now().Add(-SessionRetentionAfter)).
+		Delete(&models.PasswordReset{})
+	return result.RowsAffected, result.Error
+}
+
+// PurgeLoginAttempts deletes login attempts older than LoginAttemptRetention.
+func PurgeLoginAttempts() (int64, error) {
+	result := database.DB.Where("created_at < ?", time.Now().Add(-LoginAttemptRetention)).
+		Delete(&models.LoginAttempt{})
+	return result.RowsAffected, result.Error
+}
+
+// PurgeStreamTickets deletes stream tickets that expired without being used.
+func PurgeStreamTickets() (int64, error) {
+	result := database.DB.Where("expires_at < ?", time.Now()).Delete(&models.StreamTicket{})
+	return result.RowsAffected, result.Error
+}
+
+// StartSessionPurger runs PurgeSessions, PurgePasswordResets,
+// PurgeLoginAttempts and PurgeStreamTickets in the background until ctx is
+// done.
+func StartSessionPurger(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(SessionPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				count, err := PurgeSessions()
+				if err != nil {
+					log.Printf("Session purge failed: %v", err)
+					continue
+				}
+				if count > 0 {
+					log.Printf("Session purge: removed %d stale sessions", count)
+				}
+
+				if _, err := PurgePasswordResets(); err != nil {
+					log.Printf("Password reset purge failed: %v", err)
+				}
+				if _, err := PurgeLoginAttempts(); err != nil {
+					log.Printf("Login attempt purge failed: %v", err)
+				}
+				if _, err := PurgeStreamTickets(); err != nil {
+					log.Printf("Stream ticket purge failed: %v", err)
+				}
+			}
+		}
+	}()
+}
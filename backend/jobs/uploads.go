@@ -0,0 +1,215 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/storage"
+)
+
+// MinUploadGracePeriod is the shortest grace period a sweep that deletes
+// may use, so files users are still attaching to a listing are safe.
+const MinUploadGracePeriod = time.Hour
+
+var ErrGracePeriodTooShort = errors.New("grace period must be at least " + MinUploadGracePeriod.String())
+
+// SweepReport summarises a run of the orphaned upload sweeper.
+type SweepReport struct {
+	DryRun      bool      `json:"dry_run"`
+	GracePeriod string    `json:"grace_period"`
+	Scanned     int       `json:"scanned"`
+	Orphaned    int       `json:"orphaned"`
+	Bytes       int64     `json:"bytes"`
+	Deleted     int       `json:"deleted"`
+	Failed      int       `json:"failed"`
+	Keys        []string  `json:"keys,omitempty"`
+	FinishedAt  time.Time `json:"finished_at"`
+}
+
+// maxReportedKeys caps how many orphan keys a report lists
+const maxReportedKeys = 200
+
+// SweepUploads deletes stored files that nothing references any more and
+// that are older than the grace period: uploads never attached to a listing
+// or profile, and images dropped by UpdateListing or left behind by
+// DeleteListing. The grace period runs from when an image was detached, so
+// a listing edit in progress never loses its images. With dryRun set
+// nothing is deleted and the report shows what would be reclaimed.
+func SweepUploads(ctx context.Context, grace time.Duration, dryRun bool) (SweepReport, error) {
+	report := SweepReport{DryRun: dryRun, GracePeriod: grace.String()}
+	if !dryRun && grace < MinUploadGracePeriod {
+		return report, ErrGracePeriodTooShort
+	}
+	cutoff := time.Now().Add(-grace)
+
+	objects, err := storage.Default.List(ctx)
+	if err != nil {
+		return report, err
+	}
+	report.Scanned = len(objects)
+
+	// Anything short of a complete picture of what's in use would look like
+	// orphans, so stop before deleting anything
+	referenced, err := referencedKeys(cutoff)
+	if err != nil {
+		return report, err
+	}
+
+	// Variants of one upload live and die together: if any size is in use,
+	// keep them all
+	var uploads []models.Upload
+	if err := database.DB.Find(&uploads).Error; err != nil {
+		return report, err
+	}
+	uploadByKey := make(map[string]*models.Upload)
+	for i := range uploads {
+		keys := uploadKeys(uploads[i])
+		inUse := false
+		for _, key := range keys {
+			uploadByKey[key] = &uploads[i]
+			inUse = inUse || referenced[key]
+		}
+		if inUse {
+			for _, key := range keys {
+				referenced[key] = true
+			}
+		}
+	}
+
+	orphanedUploads := make(map[uint]bool)
+	for _, object := range objects {
+		if referenced[object.Key] || object.ModTime.After(cutoff) {
+			continue
+		}
+		if upload, ok := uploadByKey[object.Key]; ok && upload.CreatedAt.After(cutoff) {
+			continue
+		}
+
+		report.Orphaned++
+		report.Bytes += object.Size
+		if len(report.Keys) < maxReportedKeys {
+			report.Keys = append(report.Keys, object.Key)
+		}
+		if dryRun {
+			continue
+		}
+
+		if err := storage.Default.Delete(ctx, object.Key); err != nil {
+			log.Printf("Failed to delete orphaned upload %s: %v", object.Key, err)
+			report.Failed++
+			continue
+		}
+		report.Deleted++
+		if upload, ok := uploadByKey[object.Key]; ok {
+			orphanedUploads[upload.ID] = true
+		}
+	}
+
+	if len(orphanedUploads) > 0 {
+		ids := make([]uint, 0, len(orphanedUploads))
+		for id := range orphanedUploads {
+			ids = append(ids, id)
+		}
+		database.DB.Unscoped().Delete(&models.Upload{}, ids)
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// referencedKeys collects the storage keys still in use, counting images
+// detached from a listing after cutoff as in use until the grace period
+// runs out.
+func referencedKeys(cutoff time.Time) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	add := func(urls ...string) {
+		for _, url := range urls {
+			if key, ok := storage.KeyFromURL(url); ok {
+				referenced[key] = true
+			}
+		}
+	}
+
+	var images []models.ListingImage
+	if err := database.DB.Unscoped().
+		Joins("LEFT JOIN listings ON listings.id = listing_images.listing_id").
+		Where("(listing_images.deleted_at IS NULL AND listings.deleted_at IS NULL) OR "+
+			"listing_images.deleted_at > ? OR listings.deleted_at > ?", cutoff, cutoff).
+		Find(&images).Error; err != nil {
+		return nil, err
+	}
+	for _, image := range images {
+		add(image.ImageURL, image.MediumURL, image.ThumbnailURL)
+	}
+
+	var profileImages []string
+	if err := database.DB.Model(&models.User{}).Where("profile_image != ''").
+		Pluck("profile_image", &profileImages).Error; err != nil {
+		return nil, err
+	}
+	add(profileImages...)
+
+	return referenced, nil
+}
+
+func uploadKeys(upload models.Upload) []string {
+	var keys []string
+	for _, url := range []string{upload.URL, upload.MediumURL, upload.ThumbnailURL} {
+		if key, ok := storage.KeyFromURL(url); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Upload sweeper settings. UPLOAD_GC_INTERVAL and UPLOAD_GC_GRACE take Go
+// durations (e.g. "6h"); UPLOAD_GC_DRY_RUN=true only logs what would go.
+var (
+	UploadSweepInterval = 6 * time.Hour
+	UploadGracePeriod   = 24 * time.Hour
+	UploadSweepDryRun   = false
+)
+
+// StartUploadSweeper runs SweepUploads in the background until ctx is done.
+func StartUploadSweeper(ctx context.Context) {
+	if value, err := time.ParseDuration(os.Getenv("UPLOAD_GC_INTERVAL")); err == nil && value > 0 {
+		UploadSweepInterval = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("UPLOAD_GC_GRACE")); err == nil && value >= 0 {
+		UploadGracePeriod = value
+	}
+	if UploadGracePeriod < MinUploadGracePeriod {
+		log.Printf("UPLOAD_GC_GRACE is below the minimum; using %s", MinUploadGracePeriod)
+		UploadGracePeriod = MinUploadGracePeriod
+	}
+	if value, err := strconv.ParseBool(os.Getenv("UPLOAD_GC_DRY_RUN")); err == nil {
+		UploadSweepDryRun = value
+	}
+
+	go func() {
+		ticker := time.NewTicker(UploadSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, err := SweepUploads(ctx, UploadGracePeriod, UploadSweepDryRun)
+				if err != nil {
+					log.Printf("Upload sweep failed: %v", err)
+					continue
+				}
+				if report.Orphaned > 0 {
+					log.Printf("Upload sweep: %d orphaned files (%d bytes), %d deleted, dry run: %v",
+						report.Orphaned, report.Bytes, report.Deleted, report.DryRun)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,134 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/storage"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useTestStorage points the database and storage at throwaway copies and
+// returns the storage directory.
+func useTestStorage(t *testing.T) string {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1) // every connection to :memory: is its own database
+	if err := db.AutoMigrate(&models.User{}, &models.Category{}, &models.Listing{},
+		&models.ListingImage{}, &models.Upload{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	store, err := storage.NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousDB, previousStore := database.DB, storage.Default
+	database.DB, storage.Default = db, store
+	t.Cleanup(func() {
+		sqlDB.Close()
+		database.DB, storage.Default = previousDB, previousStore
+	})
+	return dir
+}
+
+// putOldFile stores a file last modified well before any grace period.
+func putOldFile(t *testing.T, dir, key string) {
+	t.Helper()
+	if err := storage.Default.Put(context.Background(), key, []byte("image"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, key), old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func seedSweepFixtures(t *testing.T, dir string) {
+	t.Helper()
+	for _, key := range []string{"live.jpg", "detached.jpg", "orphan.jpg"} {
+		putOldFile(t, dir, key)
+	}
+
+	listing := models.Listing{Title: "Desk", Price: 10, SellerID: 1, Status: models.StatusActive}
+	if err := database.DB.Create(&listing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	live := models.ListingImage{ListingID: listing.ID, ImageURL: storage.URL("live.jpg")}
+	detached := models.ListingImage{ListingID: listing.ID, ImageURL: storage.URL("detached.jpg")}
+	if err := database.DB.Create(&[]models.ListingImage{live, detached}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Dropped from the listing ten minutes ago, so still inside the grace period
+	database.DB.Unscoped().Model(&models.ListingImage{}).Where("image_url = ?", detached.ImageURL).
+		Update("deleted_at", time.Now().Add(-10*time.Minute))
+}
+
+func TestSweepUploadsDeletesOnlyOrphans(t *testing.T) {
+	dir := useTestStorage(t)
+	seedSweepFixtures(t, dir)
+
+	report, err := SweepUploads(context.Background(), time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Deleted != 1 || len(report.Keys) != 1 || report.Keys[0] != "orphan.jpg" {
+		t.Errorf("got %+v, want only orphan.jpg deleted", report)
+	}
+
+	for key, want := range map[string]bool{"live.jpg": true, "detached.jpg": true, "orphan.jpg": false} {
+		_, err := os.Stat(filepath.Join(dir, key))
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", key, exists, want)
+		}
+	}
+}
+
+func TestSweepUploadsAbortsWhenReferencesCantBeRead(t *testing.T) {
+	dir := useTestStorage(t)
+	seedSweepFixtures(t, dir)
+
+	if err := database.DB.Migrator().DropTable(&models.ListingImage{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := SweepUploads(context.Background(), time.Hour, false); err == nil {
+		t.Fatal("sweep succeeded without knowing which images are in use")
+	}
+	for _, key := range []string{"live.jpg", "detached.jpg", "orphan.jpg"} {
+		if _, err := os.Stat(filepath.Join(dir, key)); err != nil {
+			t.Errorf("%s was deleted by a failed sweep", key)
+		}
+	}
+}
+
+func TestSweepUploadsRefusesShortGracePeriod(t *testing.T) {
+	dir := useTestStorage(t)
+	seedSweepFixtures(t, dir)
+
+	if _, err := SweepUploads(context.Background(), 0, false); err != ErrGracePeriodTooShort {
+		t.Fatalf("got %v, want ErrGracePeriodTooShort", err)
+	}
+	if report, err := SweepUploads(context.Background(), 0, true); err != nil || report.Orphaned != 2 {
+		t.Errorf("dry run with no grace period: got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,82 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing email.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Default is the mailer the app sends through, set up by Init.
+var Default Mailer = NewLog()
+
+// Init picks the mail backend from MAIL_BACKEND: "log" (the default) only
+// writes messages to the server log, "file" drops them as .eml files into
+// MAIL_DIR for local runs, and "smtp" sends them for real.
+func Init() error {
+	var err error
+	switch os.Getenv("MAIL_BACKEND") {
+	case "", "log":
+		Default = NewLog()
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "./mail"
+		}
+		Default, err = NewFile(dir)
+	case "smtp":
+		Default, err = NewSMTPFromEnv()
+	default:
+		err = errors.New("unknown MAIL_BACKEND " + os.Getenv("MAIL_BACKEND"))
+	}
+	return err
+}
+
+// From is the sender address used on outgoing mail.
+func From() string {
+	if from := os.Getenv("MAIL_FROM"); from != "" {
+		return from
+	}
+	return "UF Market <no-reply@ufmarket.local>"
+}
+
+// compose renders msg as an RFC 5322 message.
+func compose(msg Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", From())
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mimeHeader(msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return buf.Bytes()
+}
+
+// mimeHeader keeps header values on one line and encodes non-ASCII text.
+func mimeHeader(value string) string {
+	value = strings.NewReplacer("\r", "", "\n", " ").Replace(value)
+	for _, r := range value {
+		if r > 127 {
+			return mime.QEncoding.Encode("UTF-8", value)
+		}
+	}
+	return value
+}
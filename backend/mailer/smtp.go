@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+)
+
+// SMTP sends mail through an SMTP relay, using STARTTLS when the server
+// offers it.
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTPFromEnv configures the relay from SMTP_HOST, SMTP_PORT (default
+// 587), SMTP_USERNAME and SMTP_PASSWORD.
+func NewSMTPFromEnv() (*SMTP, error) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil, errors.New("SMTP_HOST is required for the smtp mail backend")
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	s := &SMTP{addr: net.JoinHostPort(host, port)}
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		s.auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	return s, nil
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(From())
+	if err != nil {
+		return err
+	}
+
+	// net/smtp has no context support, so run it aside and give up on the
+	// wait when ctx is done
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, s.auth, from.Address, []string{msg.To}, compose(msg))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
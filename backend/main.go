@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"strings"
+	"time"
 	"uf-marketplace/database"
 	"uf-marketplace/handlers"
+	"uf-marketplace/jobs"
+	"uf-marketplace/mailer"
 	"uf-marketplace/middleware"
+	"uf-marketplace/models"
+	"uf-marketplace/storage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -16,42 +22,94 @@ func main() {
 	// Initialize database
 	database.InitDB()
 
-	// Create uploads directory if it doesn't exist
-	if err := os.MkdirAll("./uploads", 0755); err != nil {
-		log.Fatalf("Failed to create uploads directory: %v", err)
+	// Initialize outgoing mail (log, file or SMTP)
+	if err := mailer.Init(); err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
 	}
 
+	// Initialize upload storage (local disk or S3)
+	if err := storage.Init(); err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	// Background cleanup of uploads nothing references any more
+	jobs.StartUploadSweeper(context.Background())
+
+	// Background cleanup of expired and revoked sessions
+	jobs.StartSessionPurger(context.Background())
+
+	// Background expiry of stale listings, with a reminder to the seller first
+	jobs.StartListingExpirer(context.Background())
+
+	// Daily digests for saved searches that batch their alerts
+	jobs.StartSavedSearchDigester(context.Background())
+
 	// Initialize Gin router
 	r := gin.Default()
 
-	// Configure CORS
-	config := cors.DefaultConfig()
-	allowedOrigins := os.Getenv("CORS_ORIGINS")
-	if allowedOrigins != "" {
-		config.AllowOrigins = strings.Split(allowedOrigins, ",")
-	} else {
-		// Allow all localhost ports for development
-		config.AllowOriginFunc = func(origin string) bool {
-			return strings.HasPrefix(origin, "http://localhost:")
+	// X-Forwarded-For is only believed from the proxies in TRUSTED_PROXIES
+	// (comma-separated IPs or CIDRs). Without it ClientIP is the connecting
+	// address, so clients can't dodge the per-IP limits with a header.
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
 		}
 	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	// Configure CORS
+	config := cors.DefaultConfig()
+	config.AllowOriginFunc = middleware.AllowedOrigin
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
 	config.AllowCredentials = true
+	config.ExposeHeaders = []string{"RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}
 	r.Use(cors.New(config))
 
-	// Serve static files (uploads)
-	r.Static("/uploads", "./uploads")
+	// Serve uploaded files through the storage backend
+	r.GET("/uploads/*key", handlers.ServeUpload)
+	r.HEAD("/uploads/*key", handlers.ServeUpload)
+
+	// Token-bucket rate limits, per user when signed in and per IP
+	// otherwise. Each can be overridden with RATE_LIMIT_<NAME>=count/duration
+	apiLimit := middleware.NewRateLimit("api", 300, time.Minute)
+	authLimit := middleware.NewRateLimit("auth", 20, time.Minute)
+	listingLimit := middleware.NewRateLimit("listings", 20, time.Hour)
+	offerLimit := middleware.NewRateLimit("offers", 30, time.Hour)
+	uploadLimit := middleware.NewRateLimit("uploads", 60, time.Hour)
+	chatLimit := middleware.NewRateLimit("chats", 30, time.Hour)
+	messageLimit := middleware.NewRateLimit("messages", 30, time.Minute)
+	reportLimit := middleware.NewRateLimit("reports", 10, time.Hour)
 
 	// API routes
 	api := r.Group("/api")
+	api.Use(middleware.RateLimitMiddleware(apiLimit))
 	{
 		// Auth routes (public)
 		auth := api.Group("/auth")
+		auth.Use(middleware.RateLimitMiddleware(authLimit))
 		{
 			auth.POST("/register", handlers.Register)
 			auth.POST("/login", handlers.Login)
 			auth.GET("/me", middleware.AuthMiddleware(), handlers.GetMe)
+			auth.POST("/refresh", handlers.RefreshToken)
+			auth.POST("/logout", middleware.OptionalAuthMiddleware(), handlers.Logout)
+			auth.GET("/sessions", middleware.AuthMiddleware(), handlers.GetSessions)
+			auth.DELETE("/sessions/:id", middleware.AuthMiddleware(), handlers.RevokeSession)
+			auth.POST("/stream-ticket", middleware.AuthMiddleware(), handlers.CreateStreamTicket)
+			auth.POST("/verify-email", handlers.VerifyEmail)
+			auth.POST("/resend-verification", middleware.AuthMiddleware(), handlers.ResendVerification)
+			auth.POST("/forgot-password", handlers.ForgotPassword)
+			auth.POST("/reset-password", handlers.ResetPassword)
+			auth.POST("/2fa/verify", handlers.VerifyTwoFactorLogin)
+			auth.GET("/2fa", middleware.AuthMiddleware(), handlers.GetTwoFactorStatus)
+			auth.POST("/2fa/setup", middleware.AuthMiddleware(), handlers.SetupTwoFactor)
+			auth.POST("/2fa/enable", middleware.AuthMiddleware(), handlers.EnableTwoFactor)
+			auth.POST("/2fa/disable", middleware.AuthMiddleware(), handlers.DisableTwoFactor)
+			auth.POST("/2fa/recovery-codes", middleware.AuthMiddleware(), handlers.RegenerateRecoveryCodes)
 		}
 
 		// Categories (public)
@@ -62,35 +120,90 @@ func main() {
 		{
 			listings.GET("", middleware.OptionalAuthMiddleware(), handlers.GetListings)
 			listings.GET("/:id", middleware.OptionalAuthMiddleware(), handlers.GetListing)
-			listings.POST("", middleware.AuthMiddleware(), handlers.CreateListing)
+			listings.POST("", middleware.AuthMiddleware(), middleware.VerifiedEmailMiddleware(), middleware.RateLimitMiddleware(listingLimit), handlers.CreateListing)
 			listings.PUT("/:id", middleware.AuthMiddleware(), handlers.UpdateListing)
 			listings.DELETE("/:id", middleware.AuthMiddleware(), handlers.DeleteListing)
+
+			// Status changes and their history
+			listings.PUT("/:id/status", middleware.AuthMiddleware(), handlers.UpdateListingStatus)
+			listings.GET("/:id/history", middleware.AuthMiddleware(), handlers.GetListingStatusHistory)
+			listings.POST("/:id/renew", middleware.AuthMiddleware(), handlers.RenewListing)
+
+			// Offers
+			listings.GET("/:id/offers", middleware.AuthMiddleware(), handlers.GetListingOffers)
+			listings.POST("/:id/offers", middleware.AuthMiddleware(), middleware.RateLimitMiddleware(offerLimit), handlers.CreateOffer)
+			listings.PUT("/:id/offers/:offer_id/accept", middleware.AuthMiddleware(), handlers.AcceptOffer)
+			listings.PUT("/:id/offers/:offer_id/decline", middleware.AuthMiddleware(), handlers.DeclineOffer)
+			listings.PUT("/:id/offers/:offer_id/counter", middleware.AuthMiddleware(), handlers.CounterOffer)
+
+			// Favorites / price-drop watchers
+			listings.POST("/:id/favorite", middleware.AuthMiddleware(), handlers.FavoriteListing)
+			listings.DELETE("/:id/favorite", middleware.AuthMiddleware(), handlers.UnfavoriteListing)
+
+			// Recording the sale
+			listings.POST("/:id/sold", middleware.AuthMiddleware(), handlers.MarkListingSold)
+
+			// Reviews between buyer and seller after a sale
+			listings.POST("/:id/reviews", middleware.AuthMiddleware(), handlers.CreateReview)
 		}
 
 		// Upload route
-		api.POST("/upload", middleware.AuthMiddleware(), handlers.UploadImage)
+		api.POST("/upload", middleware.AuthMiddleware(), middleware.RateLimitMiddleware(uploadLimit), handlers.UploadImage)
 
 		// User routes
 		users := api.Group("/users")
 		{
 			users.GET("/:id", handlers.GetUser)
-			users.GET("/:id/listings", handlers.GetUserListings)
+			users.GET("/:id/listings", middleware.OptionalAuthMiddleware(), handlers.GetUserListings)
+			users.GET("/:id/reviews", handlers.GetUserReviews)
 			users.PUT("/me", middleware.AuthMiddleware(), handlers.UpdateUser)
 			users.PUT("/me/password", middleware.AuthMiddleware(), handlers.ChangePassword)
 			users.GET("/me/listings", middleware.AuthMiddleware(), handlers.GetMyListings)
+			users.GET("/me/favorites", middleware.AuthMiddleware(), handlers.GetMyFavorites)
+			users.GET("/me/reports", middleware.AuthMiddleware(), handlers.GetMyReports)
+			users.GET("/me/transactions", middleware.AuthMiddleware(), handlers.GetMyTransactions)
+			users.GET("/me/blocks", middleware.AuthMiddleware(), handlers.GetMyBlocks)
+
+			// Blocking
+			users.POST("/:id/block", middleware.AuthMiddleware(), handlers.BlockUser)
+			users.DELETE("/:id/block", middleware.AuthMiddleware(), handlers.UnblockUser)
 		}
 
+		// Real-time chat delivery
+		api.GET("/chats/ws", middleware.StreamAuthMiddleware(), handlers.ChatWebSocket)
+
 		// Chat routes
 		chats := api.Group("/chats")
 		chats.Use(middleware.AuthMiddleware())
 		{
 			chats.GET("", handlers.GetChats)
-			chats.POST("", handlers.CreateChat)
+			chats.POST("", middleware.VerifiedEmailMiddleware(), middleware.RateLimitMiddleware(chatLimit), handlers.CreateChat)
 			chats.GET("/:id", handlers.GetChat)
 			chats.GET("/:id/messages", handlers.GetChatMessages)
-			chats.POST("/:id/messages", handlers.SendMessage)
+			chats.POST("/:id/messages", middleware.RateLimitMiddleware(messageLimit), handlers.SendMessage)
+			chats.POST("/:id/mute", handlers.MuteChat)
+			chats.DELETE("/:id/mute", handlers.UnmuteChat)
 		}
 
+		// Replies to reviews
+		api.PUT("/reviews/:id/reply", middleware.AuthMiddleware(), handlers.ReplyToReview)
+
+		// Saved searches with new-match alerts
+		savedSearches := api.Group("/saved-searches")
+		savedSearches.Use(middleware.AuthMiddleware())
+		{
+			savedSearches.GET("", handlers.GetSavedSearches)
+			savedSearches.POST("", handlers.CreateSavedSearch)
+			savedSearches.PUT("/:id", handlers.UpdateSavedSearch)
+			savedSearches.DELETE("/:id", handlers.DeleteSavedSearch)
+		}
+
+		// Reporting listings, users and messages
+		api.POST("/reports", middleware.AuthMiddleware(), middleware.VerifiedEmailMiddleware(), middleware.RateLimitMiddleware(reportLimit), handlers.CreateReport)
+
+		// Real-time notification delivery
+		api.GET("/notifications/stream", middleware.StreamAuthMiddleware(), handlers.StreamNotifications)
+
 		// Notification routes
 		notifications := api.Group("/notifications")
 		notifications.Use(middleware.AuthMiddleware())
@@ -101,6 +214,37 @@ func main() {
 			notifications.PUT("/read-all", handlers.MarkAllNotificationsRead)
 			notifications.DELETE("/:id", handlers.DeleteNotification)
 		}
+
+		// Admin and moderator routes, each guarded by the permission it needs
+		admin := api.Group("/admin")
+		admin.Use(middleware.AuthMiddleware())
+		{
+			admin.GET("/uploads/orphans", middleware.RequirePermission(models.PermUploadsManage), handlers.GetOrphanedUploads)
+			admin.POST("/uploads/sweep", middleware.RequirePermission(models.PermUploadsManage), handlers.SweepOrphanedUploads)
+
+			admin.GET("/users", middleware.RequirePermission(models.PermUsersView), handlers.AdminGetUsers)
+			admin.GET("/users/:id", middleware.RequirePermission(models.PermUsersView), handlers.AdminGetUser)
+			admin.POST("/users/:id/suspend", middleware.RequirePermission(models.PermUsersSuspend), handlers.SuspendUser)
+			admin.POST("/users/:id/ban", middleware.RequirePermission(models.PermUsersBan), handlers.BanUser)
+			admin.POST("/users/:id/reinstate", middleware.RequirePermission(models.PermUsersSuspend), handlers.ReinstateUser)
+			admin.PUT("/users/:id/role", middleware.RequirePermission(models.PermRolesManage), handlers.SetUserRole)
+			admin.POST("/users/:id/unlock", middleware.RequirePermission(models.PermUsersUnlock), handlers.UnlockUser)
+
+			admin.POST("/listings/:id/remove", middleware.RequirePermission(models.PermListingsRemove), handlers.RemoveListing)
+			admin.POST("/listings/:id/restore", middleware.RequirePermission(models.PermListingsRemove), handlers.RestoreListing)
+
+			admin.POST("/categories", middleware.RequirePermission(models.PermCategoriesManage), handlers.CreateCategory)
+			admin.PUT("/categories/:id", middleware.RequirePermission(models.PermCategoriesManage), handlers.UpdateCategory)
+			admin.DELETE("/categories/:id", middleware.RequirePermission(models.PermCategoriesManage), handlers.DeleteCategory)
+
+			admin.GET("/reports", middleware.RequirePermission(models.PermReportsView), handlers.GetReportQueue)
+			admin.GET("/reports/:id", middleware.RequirePermission(models.PermReportsView), handlers.GetReport)
+			admin.PUT("/reports/:id", middleware.RequirePermission(models.PermReportsManage), handlers.UpdateReport)
+
+			admin.GET("/stats", middleware.RequirePermission(models.PermStatsView), handlers.GetAdminStats)
+			admin.GET("/audit-log", middleware.RequirePermission(models.PermAuditView), handlers.GetAuditLog)
+			admin.GET("/login-attempts", middleware.RequirePermission(models.PermAuditView), handlers.GetLoginAttempts)
+		}
 	}
 
 	port := os.Getenv("PORT")
@@ -1,8 +1,12 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
 	"uf-marketplace/utils"
 
 	"github.com/gin-gonic/gin"
@@ -24,16 +28,14 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := utils.ValidateToken(tokenString)
+		claims, role, err := authenticate(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		c.Set("userID", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("isAdmin", claims.IsAdmin)
+		setClaims(c, claims, role)
 
 		c.Next()
 	}
@@ -53,28 +55,135 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := utils.ValidateToken(tokenString)
+		claims, role, err := authenticate(tokenString)
 		if err != nil {
 			c.Next()
 			return
 		}
 
-		c.Set("userID", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("isAdmin", claims.IsAdmin)
+		setClaims(c, claims, role)
 
 		c.Next()
 	}
 }
 
-func AdminMiddleware() gin.HandlerFunc {
+// RequirePermission lets the request through only if the signed-in user's
+// role grants permission. It must run after AuthMiddleware.
+func RequirePermission(permission models.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		isAdmin, exists := c.Get("isAdmin")
-		if !exists || !isAdmin.(bool) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		if !HasPermission(c, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to do this", "permission": permission})
 			c.Abort()
 			return
 		}
 		c.Next()
 	}
 }
+
+// HasPermission reports whether the signed-in user's role grants
+// permission, for handlers that allow owners or staff.
+func HasPermission(c *gin.Context, permission models.Permission) bool {
+	role, _ := c.Get("role")
+	r, ok := role.(models.Role)
+	return ok && r.Can(permission)
+}
+
+// StreamAuthMiddleware accepts the same JWT as AuthMiddleware, or a
+// single-use ticket from POST /auth/stream-ticket in the "ticket" query
+// param, since browsers can't set headers on a WebSocket or EventSource
+// handshake. Tickets keep access tokens out of URLs and request logs.
+func StreamAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var claims *utils.Claims
+		var role models.Role
+		var err error
+
+		if tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); tokenString != "" {
+			claims, role, err = authenticate(tokenString)
+		} else if ticket := c.Query("ticket"); ticket != "" {
+			claims, role, err = redeemStreamTicket(ticket)
+		} else {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token or stream ticket required"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		setClaims(c, claims, role)
+
+		c.Next()
+	}
+}
+
+var errSessionRevoked = errors.New("session revoked")
+
+// authenticate validates the JWT, makes sure the session it was issued for
+// hasn't been signed out or revoked since, and looks up the user's current
+// role so role changes apply to the very next request.
+func authenticate(tokenString string) (*utils.Claims, models.Role, error) {
+	claims, err := utils.ValidateToken(tokenString)
+	if err != nil {
+		return nil, "", err
+	}
+
+	account, err := activeSession(claims.UserID, claims.SessionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return claims, account.Role, nil
+}
+
+// redeemStreamTicket spends a stream ticket and returns the claims of the
+// session it was issued to, which must still be active.
+func redeemStreamTicket(ticket string) (*utils.Claims, models.Role, error) {
+	var record models.StreamTicket
+	if result := database.DB.Where("ticket_hash = ? AND expires_at > ?", utils.HashToken(ticket), time.Now()).
+		First(&record); result.Error != nil {
+		return nil, "", result.Error
+	}
+
+	// Only the request that deletes it gets to use it
+	if result := database.DB.Delete(&record); result.Error != nil || result.RowsAffected == 0 {
+		return nil, "", utils.ErrInvalidToken
+	}
+
+	account, err := activeSession(record.UserID, record.SessionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &utils.Claims{UserID: record.UserID, Email: account.Email, SessionID: record.SessionID}, account.Role, nil
+}
+
+type sessionAccount struct {
+	Role  models.Role
+	Email string
+}
+
+// activeSession looks up the user behind a session that hasn't been signed
+// out, revoked or expired.
+func activeSession(userID, sessionID uint) (sessionAccount, error) {
+	var account sessionAccount
+	result := database.DB.Model(&models.Session{}).
+		Select("users.role, users.email").
+		Joins("JOIN users ON users.id = sessions.user_id AND users.deleted_at IS NULL").
+		Where("sessions.id = ? AND sessions.user_id = ?", sessionID, userID).
+		Where("sessions.revoked_at IS NULL AND sessions.expires_at > ?", time.Now()).
+		Scan(&account)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return account, errSessionRevoked
+	}
+	return account, nil
+}
+
+func setClaims(c *gin.Context, claims *utils.Claims, role models.Role) {
+	c.Set("userID", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Set("role", role)
+	c.Set("sessionID", claims.SessionID)
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"os"
+	"strings"
+)
+
+// AllowedOrigin reports whether a browser page at origin may call the API:
+// one of the comma-separated CORS_ORIGINS, or any localhost port when that
+// isn't set, for development.
+func AllowedOrigin(origin string) bool {
+	allowedOrigins := os.Getenv("CORS_ORIGINS")
+	if allowedOrigins == "" {
+		return strings.HasPrefix(origin, "http://localhost:")
+	}
+
+	for _, allowed := range strings.Split(allowedOrigins, ",") {
+		if strings.TrimSpace(allowed) == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"uf-marketplace/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit is a token bucket: callers can make Burst requests at once,
+// and the bucket refills completely over Per. Buckets with different
+// names are counted separately, so a request can pass through several.
+type RateLimit struct {
+	Name  string
+	Burst int
+	Per   time.Duration
+}
+
+// Disabled limits let everything through.
+func (l RateLimit) Disabled() bool {
+	return l.Burst <= 0 || l.Per <= 0
+}
+
+// Rate is how many tokens the bucket regains per second.
+func (l RateLimit) Rate() float64 {
+	return float64(l.Burst) / l.Per.Seconds()
+}
+
+// RateLimitResult is the state of a bucket after taking from it.
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	ResetAfter time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, when not allowed
+}
+
+// RateLimitStore keeps the buckets. Take spends a token from key's bucket
+// for limit, refilling it for the time since it was last used.
+type RateLimitStore interface {
+	Take(ctx context.Context, limit RateLimit, key string, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimitStorage is where every RateLimitMiddleware keeps its buckets.
+// It is in-memory by default, which is right for a single server; swap it
+// for a shared store before RateLimitMiddleware is used when running more.
+var RateLimitStorage RateLimitStore = NewMemoryRateLimitStore()
+
+// NewRateLimit returns a limit of burst requests refilled over per. It can
+// be overridden with RATE_LIMIT_<NAME>, e.g. RATE_LIMIT_MESSAGES=60/1m, or
+// turned off with RATE_LIMIT_<NAME>=off.
+func NewRateLimit(name string, burst int, per time.Duration) RateLimit {
+	limit := RateLimit{Name: name, Burst: burst, Per: per}
+
+	variable := "RATE_LIMIT_" + strings.ToUpper(name)
+	value := strings.TrimSpace(os.Getenv(variable))
+	if value == "" {
+		return limit
+	}
+	if value == "off" {
+		limit.Burst = 0
+		return limit
+	}
+
+	count, window, ok := strings.Cut(value, "/")
+	burstValue, err := strconv.Atoi(count)
+	perValue, perErr := time.ParseDuration(window)
+	if !ok || err != nil || perErr != nil || burstValue <= 0 || perValue <= 0 {
+		log.Printf("Ignoring %s=%q; expected a count and duration like 60/1m", variable, value)
+		return limit
+	}
+
+	limit.Burst = burstValue
+	limit.Per = perValue
+	return limit
+}
+
+// RateLimitMiddleware throttles requests with limit, per user when the
+// request carries a valid token and per IP otherwise. Every response gets
+// RateLimit-* headers; requests over the limit get 429 with Retry-After.
+func RateLimitMiddleware(limit RateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit.Disabled() {
+			c.Next()
+			return
+		}
+
+		result, err := RateLimitStorage.Take(c.Request.Context(), limit, rateLimitKey(c), time.Now())
+		if err != nil {
+			// Better to serve the request than to fail because the store did
+			log.Printf("Rate limit %s: %v", limit.Name, err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Burst, int(math.Ceil(limit.Per.Seconds()))))
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many requests. Please slow down and try again shortly.",
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitKey identifies who a request counts against. The token is only
+// checked for its signature, not its session, so the limiter can run
+// before AuthMiddleware without a database lookup.
+func rateLimitKey(c *gin.Context) string {
+	if userID := c.GetUint("userID"); userID != 0 {
+		return "user:" + strconv.FormatUint(uint64(userID), 10)
+	}
+
+	if tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); tokenString != "" {
+		if claims, err := utils.ValidateToken(tokenString); err == nil {
+			return "user:" + strconv.FormatUint(uint64(claims.UserID), 10)
+		}
+	}
+
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
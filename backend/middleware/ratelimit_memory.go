@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often idle buckets are dropped from memory.
+const rateLimitSweepInterval = time.Minute
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // once past this the bucket is full and can be dropped
+}
+
+// MemoryRateLimitStore keeps buckets in this process. A bucket that has
+// refilled is the same as one that was never used, so those are dropped
+// as it goes.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, limit RateLimit, key string, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= rateLimitSweepInterval {
+		s.sweep(now)
+	}
+
+	burst := float64(limit.Burst)
+	rate := limit.Rate()
+
+	id := limit.Name + ":" + key
+	bucket, ok := s.buckets[id]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, updated: now}
+		s.buckets[id] = bucket
+	}
+
+	if elapsed := now.Sub(bucket.updated).Seconds(); elapsed > 0 {
+		bucket.tokens = math.Min(burst, bucket.tokens+elapsed*rate)
+		bucket.updated = now
+	}
+
+	var result RateLimitResult
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - bucket.tokens) / rate)
+	}
+
+	result.Remaining = int(bucket.tokens)
+	result.ResetAfter = secondsToDuration((burst - bucket.tokens) / rate)
+	bucket.full = now.Add(result.ResetAfter)
+
+	return result, nil
+}
+
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for id, bucket := range s.buckets {
+		if !now.Before(bucket.full) {
+			delete(s.buckets, id)
+		}
+	}
+	s.lastSweep = now
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
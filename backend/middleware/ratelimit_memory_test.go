@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	limit := RateLimit{Name: "test", Burst: 3, Per: 3 * time.Second} // one token a second
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name       string
+		key        string
+		at         time.Duration
+		allowed    bool
+		remaining  int
+		resetAfter time.Duration
+		retryAfter time.Duration
+	}{
+		{"first request", "a", 0, true, 2, time.Second, 0},
+		{"second in burst", "a", 0, true, 1, 2 * time.Second, 0},
+		{"last in burst", "a", 0, true, 0, 3 * time.Second, 0},
+		{"burst spent", "a", 0, false, 0, 3 * time.Second, time.Second},
+		{"half refilled", "a", 500 * time.Millisecond, false, 0, 2500 * time.Millisecond, 500 * time.Millisecond},
+		{"other key unaffected", "b", 500 * time.Millisecond, true, 2, time.Second, 0},
+		{"one token back", "a", time.Second, true, 0, 3 * time.Second, 0},
+		{"refill capped at burst", "a", time.Minute, true, 2, time.Second, 0},
+	}
+
+	store := NewMemoryRateLimitStore()
+	for _, step := range steps {
+		result, err := store.Take(context.Background(), limit, step.key, start.Add(step.at))
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if result.Allowed != step.allowed || result.Remaining != step.remaining ||
+			result.ResetAfter != step.resetAfter || result.RetryAfter != step.retryAfter {
+			t.Errorf("%s: got %+v, want allowed=%v remaining=%d reset=%v retry=%v", step.name, result,
+				step.allowed, step.remaining, step.resetAfter, step.retryAfter)
+		}
+	}
+}
+
+func TestMemoryRateLimitStoreSeparatesLimits(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	strict := RateLimit{Name: "strict", Burst: 1, Per: time.Minute}
+	loose := RateLimit{Name: "loose", Burst: 10, Per: time.Minute}
+
+	store.Take(context.Background(), strict, "user:1", now)
+	if result, _ := store.Take(context.Background(), strict, "user:1", now); result.Allowed {
+		t.Fatal("strict limit allowed a second request")
+	}
+	if result, _ := store.Take(context.Background(), loose, "user:1", now); !result.Allowed {
+		t.Fatal("a spent bucket in one limit throttled another")
+	}
+}
+
+func TestMemoryRateLimitStoreSweepsFullBuckets(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	short := RateLimit{Name: "short", Burst: 5, Per: time.Second}
+	long := RateLimit{Name: "long", Burst: 5, Per: time.Hour}
+
+	store.Take(context.Background(), short, "a", now)
+	store.Take(context.Background(), long, "a", now)
+	store.Take(context.Background(), short, "b", now.Add(2*rateLimitSweepInterval))
+
+	if _, ok := store.buckets["short:a"]; ok {
+		t.Error("refilled bucket was not swept")
+	}
+	if _, ok := store.buckets["long:a"]; !ok {
+		t.Error("bucket still refilling was swept")
+	}
+}
+
+func TestNewRateLimitFromEnv(t *testing.T) {
+	tests := []struct {
+		value    string
+		burst    int
+		per      time.Duration
+		disabled bool
+	}{
+		{"", 30, time.Minute, false},
+		{"60/1h", 60, time.Hour, false},
+		{"off", 0, time.Minute, true},
+		{"lots", 30, time.Minute, false},
+		{"0/1m", 30, time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("RATE_LIMIT_MESSAGES", tt.value)
+		limit := NewRateLimit("messages", 30, time.Minute)
+		if limit.Burst != tt.burst || limit.Per != tt.per || limit.Disabled() != tt.disabled {
+			t.Errorf("RATE_LIMIT_MESSAGES=%q: got %+v", tt.value, limit)
+		}
+	}
+}
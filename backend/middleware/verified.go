@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifiedEmailMiddleware only lets users who have confirmed their email
+// through. It must run after AuthMiddleware.
+func VerifiedEmailMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		if result := database.DB.Select("id", "email_verified_at").First(&user, c.GetUint("userID")); result.Error != nil || !user.IsVerified() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your UF email address first"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
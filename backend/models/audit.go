@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditUserSuspend    AuditAction = "user.suspend"
+	AuditUserBan        AuditAction = "user.ban"
+	AuditUserReinstate  AuditAction = "user.reinstate"
+	AuditUserUnlock     AuditAction = "user.unlock"
+	AuditUserSetAdmin   AuditAction = "user.set_admin" // before roles replaced is_admin
+	AuditUserSetRole    AuditAction = "user.set_role"
+	AuditListingRemove  AuditAction = "listing.remove"
+	AuditListingRestore AuditAction = "listing.restore"
+	AuditListingDelete  AuditAction = "listing.delete"
+	AuditListingStatus  AuditAction = "listing.status"
+	AuditCategoryCreate AuditAction = "category.create"
+	AuditCategoryUpdate AuditAction = "category.update"
+	AuditCategoryDelete AuditAction = "category.delete"
+	AuditUploadsSweep   AuditAction = "uploads.sweep"
+	AuditReportUpdate   AuditAction = "report.update"
+)
+
+// AuditLog is an append-only record of an admin action. The database
+// rejects updates and deletes on this table (see database/audit.go).
+type AuditLog struct {
+	ID         uint        `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time   `gorm:"index" json:"created_at"`
+	ActorID    uint        `gorm:"index;not null" json:"actor_id"`
+	Actor      User        `gorm:"foreignKey:ActorID" json:"actor"`
+	Action     AuditAction `gorm:"index;not null" json:"action"`
+	TargetType string      `gorm:"index:idx_audit_target" json:"target_type"`
+	TargetID   uint        `gorm:"index:idx_audit_target" json:"target_id"`
+	Reason     string      `json:"reason"`
+	Details    string      `json:"details"` // JSON snapshot of what changed
+	IPAddress  string      `json:"ip_address"`
+}
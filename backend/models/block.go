@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// UserBlock stops Blocked from messaging Blocker or seeing their listings.
+type UserBlock struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	BlockerID uint      `gorm:"not null;uniqueIndex:idx_user_block" json:"blocker_id"`
+	BlockedID uint      `gorm:"not null;uniqueIndex:idx_user_block;index" json:"blocked_id"`
+	Blocked   User      `gorm:"foreignKey:BlockedID" json:"-"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Favorite marks a listing as watched by a user. Watchers are notified when
+// the seller lowers the price.
+type Favorite struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_favorite_user_listing" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+	ListingID uint      `gorm:"not null;uniqueIndex:idx_favorite_user_listing;index" json:"listing_id"`
+	Listing   Listing   `gorm:"foreignKey:ListingID" json:"listing"`
+}
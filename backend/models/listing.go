@@ -10,10 +10,16 @@ type ListingStatus string
 
 const (
 	StatusActive   ListingStatus = "active"
+	StatusReserved ListingStatus = "reserved" // held for a buyer, no new offers
+	StatusPending  ListingStatus = "pending"  // sale agreed, waiting on the handoff
 	StatusSold     ListingStatus = "sold"
 	StatusInactive ListingStatus = "inactive"
+	StatusRemoved  ListingStatus = "removed" // taken down by an admin
 )
 
+// ListingConditions are the accepted values for Listing.Condition.
+var ListingConditions = []string{"new", "like_new", "good", "fair", "poor"}
+
 type Listing struct {
 	ID          uint           `gorm:"primarykey" json:"id"`
 	CreatedAt   time.Time      `json:"created_at"`
@@ -31,6 +37,21 @@ type Listing struct {
 	Condition   string         `json:"condition"` // new, like_new, good, fair, poor
 	Location    string         `json:"location"`
 	Views       int            `gorm:"default:0" json:"views"`
+
+	// Why an admin removed the listing, shown to the seller
+	ModerationReason string `json:"moderation_reason,omitempty"`
+	// Set while enough open reports are waiting on review
+	HiddenAt *time.Time `json:"hidden_at,omitempty"`
+	// Who the listing was sold to, once it is sold
+	BuyerID *uint `json:"buyer_id,omitempty"`
+	// When the listing drops out of search and is made inactive; renewing
+	// pushes it back by the category's listing lifetime
+	ExpiresAt        *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	ExpiryRemindedAt *time.Time `json:"-"`
+
+	// Filled in by full-text search only; matched terms are wrapped in <mark>
+	TitleHighlight     string `gorm:"->;-:migration" json:"title_highlight,omitempty"`
+	DescriptionSnippet string `gorm:"->;-:migration" json:"description_snippet,omitempty"`
 }
 
 type ListingImage struct {
@@ -40,6 +61,13 @@ type ListingImage struct {
 	ListingID uint           `gorm:"not null" json:"listing_id"`
 	ImageURL  string         `gorm:"not null" json:"image_url"`
 	IsPrimary bool           `gorm:"default:false" json:"is_primary"`
+
+	// Resized variants and the dimensions of ImageURL, filled in from the
+	// matching Upload
+	MediumURL    string `json:"medium_url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
 }
 
 type Category struct {
@@ -50,4 +78,18 @@ type Category struct {
 	Name        string         `gorm:"uniqueIndex;not null" json:"name"`
 	Description string         `json:"description"`
 	Icon        string         `json:"icon"`
+	// How long listings in this category stay up before they expire; 0
+	// uses DefaultListingLifetimeDays
+	ListingLifetimeDays int `gorm:"default:0" json:"listing_lifetime_days"`
+}
+
+const DefaultListingLifetimeDays = 30
+
+// ListingLifetime is how long a new or renewed listing in c stays active.
+func (c Category) ListingLifetime() time.Duration {
+	days := c.ListingLifetimeDays
+	if days <= 0 {
+		days = DefaultListingLifetimeDays
+	}
+	return time.Duration(days) * 24 * time.Hour
 }
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+)
+
+// StatusActor is who asked for a listing status change.
+type StatusActor string
+
+const (
+	ActorSeller StatusActor = "seller"
+	ActorBuyer  StatusActor = "buyer" // accepting a seller's counter offer
+	ActorAdmin  StatusActor = "admin"
+	ActorSystem StatusActor = "system" // background jobs
+)
+
+// listingTransitions lists, for each status, the statuses a listing may move
+// to and who may move it there. Sold is final for everyone but admins, and
+// a sold listing that admins removed can't be restored since its
+// Transaction still stands.
+var listingTransitions = map[ListingStatus]map[ListingStatus][]StatusActor{
+	StatusActive: {
+		StatusReserved: {ActorSeller, ActorAdmin},
+		StatusPending:  {ActorSeller, ActorAdmin},
+		StatusSold:     {ActorSeller, ActorBuyer, ActorAdmin},
+		StatusInactive: {ActorSeller, ActorAdmin, ActorSystem},
+		StatusRemoved:  {ActorAdmin},
+	},
+	StatusReserved: {
+		StatusActive:   {ActorSeller, ActorAdmin},
+		StatusPending:  {ActorSeller, ActorAdmin},
+		StatusSold:     {ActorSeller, ActorAdmin},
+		StatusInactive: {ActorSeller, ActorAdmin, ActorSystem},
+		StatusRemoved:  {ActorAdmin},
+	},
+	StatusPending: {
+		StatusActive:   {ActorSeller, ActorAdmin},
+		StatusSold:     {ActorSeller, ActorAdmin},
+		StatusInactive: {ActorSeller, ActorAdmin},
+		StatusRemoved:  {ActorAdmin},
+	},
+	StatusInactive: {
+		StatusActive:  {ActorSeller, ActorAdmin},
+		StatusRemoved: {ActorAdmin},
+	},
+	StatusSold: {
+		StatusRemoved: {ActorAdmin},
+	},
+	StatusRemoved: {
+		StatusActive:   {ActorAdmin},
+		StatusInactive: {ActorAdmin},
+	},
+}
+
+// IsValid reports whether s is one of the known listing statuses.
+func (s ListingStatus) IsValid() bool {
+	_, ok := listingTransitions[s]
+	return ok
+}
+
+// CanTransition reports whether actor may move a listing from one status to
+// another.
+func CanTransition(from, to ListingStatus, actor StatusActor) bool {
+	for _, allowed := range listingTransitions[from][to] {
+		if allowed == actor {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStatuses lists the statuses actor may move a listing in s to.
+func (s ListingStatus) NextStatuses(actor StatusActor) []ListingStatus {
+	next := []ListingStatus{}
+	for _, to := range []ListingStatus{StatusActive, StatusReserved, StatusPending, StatusSold, StatusInactive, StatusRemoved} {
+		if CanTransition(s, to, actor) {
+			next = append(next, to)
+		}
+	}
+	return next
+}
+
+// ListingStatusChange is one entry in a listing's status history.
+type ListingStatusChange struct {
+	ID          uint          `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	ListingID   uint          `gorm:"index;not null" json:"listing_id"`
+	FromStatus  ListingStatus `json:"from_status"` // empty when the listing was created
+	ToStatus    ListingStatus `gorm:"not null" json:"to_status"`
+	Actor       StatusActor   `gorm:"not null" json:"actor"`
+	ChangedByID *uint         `json:"changed_by_id,omitempty"`
+	ChangedBy   *User         `gorm:"foreignKey:ChangedByID" json:"changed_by,omitempty"`
+	Reason      string        `json:"reason,omitempty"`
+}
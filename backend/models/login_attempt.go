@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+type LoginOutcome string
+
+const (
+	LoginSuccess      LoginOutcome = "success"
+	LoginBadPassword  LoginOutcome = "bad_password"
+	LoginBadCode      LoginOutcome = "bad_code" // right password, wrong two-factor code
+	LoginUnknownEmail LoginOutcome = "unknown_email"
+	LoginLockedOut    LoginOutcome = "locked_out" // refused without checking the password
+	LoginRestricted   LoginOutcome = "restricted" // right password, but suspended or banned
+	LoginTwoFactor    LoginOutcome = "two_factor" // right password, waiting for the code
+)
+
+// LoginFailures are the outcomes that count towards backoff and lockout.
+var LoginFailures = []LoginOutcome{LoginBadPassword, LoginBadCode, LoginUnknownEmail}
+
+// LoginAttempt records every sign-in attempt, including ones for unknown
+// emails, so failures can be throttled per email and per IP and audited
+// later. A successful sign-in or an admin unlock sets ClearedAt on the
+// account's earlier failures so they stop counting.
+type LoginAttempt struct {
+	ID        uint         `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time    `gorm:"index" json:"created_at"`
+	UserID    uint         `gorm:"index" json:"user_id"`
+	Email     string       `gorm:"index;not null" json:"email"`
+	IPAddress string       `gorm:"index" json:"ip_address"`
+	UserAgent string       `json:"user_agent"`
+	Outcome   LoginOutcome `gorm:"index;not null" json:"outcome"`
+	ClearedAt *time.Time   `json:"cleared_at,omitempty"`
+}
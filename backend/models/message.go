@@ -33,6 +33,15 @@ type Chat struct {
 	Seller     User           `gorm:"foreignKey:SellerID" json:"seller"`
 	Messages   []Message      `gorm:"foreignKey:ChatID" json:"messages,omitempty"`
 	LastMessage *Message      `gorm:"-" json:"last_message,omitempty"`
+
+	// Participants who muted the chat get no new-message notifications
+	BuyerMuted  bool `gorm:"default:false" json:"buyer_muted"`
+	SellerMuted bool `gorm:"default:false" json:"seller_muted"`
+}
+
+// IsMutedBy reports whether userID muted the chat.
+func (c *Chat) IsMutedBy(userID uint) bool {
+	return (c.BuyerID == userID && c.BuyerMuted) || (c.SellerID == userID && c.SellerMuted)
 }
 
 type ChatResponse struct {
@@ -45,6 +54,8 @@ type ChatResponse struct {
 	Seller      UserResponse `json:"seller"`
 	LastMessage *Message     `json:"last_message,omitempty"`
 	UnreadCount int          `json:"unread_count"`
+	Muted       bool         `json:"muted"`
+	Blocked     bool         `json:"blocked"` // either side blocked the other
 	CreatedAt   time.Time    `json:"created_at"`
 	UpdatedAt   time.Time    `json:"updated_at"`
 }
@@ -13,6 +13,21 @@ const (
 	NotificationNewOffer     NotificationType = "new_offer"
 	NotificationListingSold  NotificationType = "listing_sold"
 	NotificationPriceDropped NotificationType = "price_dropped"
+
+	NotificationOfferCountered NotificationType = "offer_countered"
+	NotificationOfferAccepted  NotificationType = "offer_accepted"
+	NotificationOfferDeclined  NotificationType = "offer_declined"
+
+	NotificationSecurity        NotificationType = "security"
+	NotificationListingRemoved  NotificationType = "listing_removed"
+	NotificationListingHidden   NotificationType = "listing_hidden"
+	NotificationListingStatus   NotificationType = "listing_status"
+	NotificationListingExpiring NotificationType = "listing_expiring"
+	NotificationListingExpired  NotificationType = "listing_expired"
+	NotificationReportUpdate    NotificationType = "report_update"
+	NotificationNewReview       NotificationType = "new_review"
+	NotificationReviewReply     NotificationType = "review_reply"
+	NotificationSavedSearch     NotificationType = "saved_search"
 )
 
 type Notification struct {
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OfferStatus string
+
+const (
+	OfferPending   OfferStatus = "pending"
+	OfferCountered OfferStatus = "countered"
+	OfferAccepted  OfferStatus = "accepted"
+	OfferDeclined  OfferStatus = "declined"
+)
+
+type Offer struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	ListingID     uint           `gorm:"not null;index" json:"listing_id"`
+	Listing       Listing        `gorm:"foreignKey:ListingID" json:"-"`
+	BuyerID       uint           `gorm:"not null;index" json:"buyer_id"`
+	Buyer         User           `gorm:"foreignKey:BuyerID" json:"buyer"`
+	Amount        float64        `gorm:"not null" json:"amount"`
+	Message       string         `json:"message"`
+	CounterAmount *float64       `json:"counter_amount,omitempty"`
+	CounterNote   string         `json:"counter_note,omitempty"`
+	Status        OfferStatus    `gorm:"default:'pending';index" json:"status"`
+	RespondedAt   *time.Time     `json:"responded_at,omitempty"`
+}
+
+// IsOpen reports whether the offer is still waiting on a response from
+// either the seller (pending) or the buyer (countered).
+func (o *Offer) IsOpen() bool {
+	return o.Status == OfferPending || o.Status == OfferCountered
+}
+
+// FinalAmount is the price both sides agreed on once the offer is accepted.
+func (o *Offer) FinalAmount() float64 {
+	if o.CounterAmount != nil {
+		return *o.CounterAmount
+	}
+	return o.Amount
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordReset records every forgot-password request, including ones for
+// unknown emails, so requests can be rate limited per email and per IP.
+// Only a hash of the emailed token is kept.
+type PasswordReset struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	Email     string     `gorm:"index;not null" json:"email"`
+	TokenHash string     `gorm:"index" json:"-"`
+	IPAddress string     `gorm:"index" json:"ip_address"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
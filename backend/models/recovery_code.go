@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode is a one-time code that stands in for a two-factor code when
+// the authenticator is lost. Only a hash is stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `gorm:"index;not null" json:"-"`
+	CodeHash  string     `gorm:"index;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
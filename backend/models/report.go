@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+)
+
+type ReportTarget string
+
+const (
+	ReportTargetListing ReportTarget = "listing"
+	ReportTargetUser    ReportTarget = "user"
+	ReportTargetMessage ReportTarget = "message"
+)
+
+type ReportReason string
+
+const (
+	ReportScam          ReportReason = "scam"
+	ReportProhibited    ReportReason = "prohibited_item"
+	ReportHarassment    ReportReason = "harassment"
+	ReportSpam          ReportReason = "spam"
+	ReportInappropriate ReportReason = "inappropriate"
+	ReportOther         ReportReason = "other"
+)
+
+// ReportReasons are the accepted values for Report.Reason.
+var ReportReasons = []ReportReason{ReportScam, ReportProhibited, ReportHarassment, ReportSpam, ReportInappropriate, ReportOther}
+
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportReviewing ReportStatus = "reviewing"
+	ReportActioned  ReportStatus = "actioned"
+	ReportDismissed ReportStatus = "dismissed"
+)
+
+// Report flags a listing, user or message for moderators to look at.
+type Report struct {
+	ID             uint         `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	ReporterID     uint         `gorm:"index;not null" json:"reporter_id"`
+	Reporter       User         `gorm:"foreignKey:ReporterID" json:"reporter"`
+	TargetType     ReportTarget `gorm:"index:idx_report_target;not null" json:"target_type"`
+	TargetID       uint         `gorm:"index:idx_report_target;not null" json:"target_id"`
+	Reason         ReportReason `gorm:"not null" json:"reason"`
+	Details        string       `json:"details"`
+	Status         ReportStatus `gorm:"index;default:'open'" json:"status"`
+	ReviewerID     *uint        `json:"reviewer_id,omitempty"`
+	ResolutionNote string       `json:"resolution_note,omitempty"`
+	ResolvedAt     *time.Time   `json:"resolved_at,omitempty"`
+}
+
+func (r *Report) IsOpen() bool {
+	return r.Status == ReportOpen || r.Status == ReportReviewing
+}
+
+// CanMoveTo reports whether a moderator may move the report to next.
+// Resolved reports are final.
+func (r *Report) CanMoveTo(next ReportStatus) bool {
+	switch r.Status {
+	case ReportOpen:
+		return next == ReportReviewing || next == ReportActioned || next == ReportDismissed
+	case ReportReviewing:
+		return next == ReportActioned || next == ReportDismissed
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// Review is one side's rating of the other after a sale. The buyer and the
+// seller of a listing can each leave one, and the person reviewed can
+// answer it publicly.
+type Review struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ListingID  uint       `gorm:"uniqueIndex:idx_review_listing_reviewer;not null" json:"listing_id"`
+	Listing    Listing    `gorm:"foreignKey:ListingID" json:"listing"`
+	ChatID     uint       `gorm:"not null" json:"chat_id"`
+	ReviewerID uint       `gorm:"uniqueIndex:idx_review_listing_reviewer;not null" json:"reviewer_id"`
+	Reviewer   User       `gorm:"foreignKey:ReviewerID" json:"reviewer"`
+	RevieweeID uint       `gorm:"index;not null" json:"reviewee_id"`
+	Role       string     `gorm:"not null" json:"role"` // what the reviewee was in the sale: buyer or seller
+	Rating     int        `gorm:"not null" json:"rating"`
+	Comment    string     `json:"comment"`
+	Reply      string     `json:"reply,omitempty"`
+	RepliedAt  *time.Time `json:"replied_at,omitempty"`
+}
+
+// RatingSummary aggregates the reviews a user has received.
+type RatingSummary struct {
+	Average float64 `json:"rating"`
+	Count   int64   `json:"review_count"`
+}
@@ -0,0 +1,81 @@
+package models
+
+// Role decides what a user may do beyond using the marketplace. It's
+// looked up on every request, so changes apply immediately.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator" // works the report queue, can't manage the site
+	RoleAdmin     Role = "admin"
+)
+
+// Roles are the accepted values for User.Role, least privileged first.
+var Roles = []Role{RoleUser, RoleModerator, RoleAdmin}
+
+// Permission is a single privileged action, checked with
+// middleware.RequirePermission or Role.Can.
+type Permission string
+
+const (
+	PermListingsModerate Permission = "listings.moderate" // see and change anyone's listing status
+	PermListingsRemove   Permission = "listings.remove"
+	PermListingsDelete   Permission = "listings.delete"
+	PermReportsView      Permission = "reports.view"
+	PermReportsManage    Permission = "reports.manage"
+	PermUsersView        Permission = "users.view"
+	PermUsersSuspend     Permission = "users.suspend"
+	PermUsersUnlock      Permission = "users.unlock" // clear a sign-in lockout
+	PermUsersBan         Permission = "users.ban"
+	PermRolesManage      Permission = "roles.manage"
+	PermCategoriesManage Permission = "categories.manage"
+	PermUploadsManage    Permission = "uploads.manage"
+	PermStatsView        Permission = "stats.view"
+	PermAuditView        Permission = "audit.view"
+)
+
+var moderatorPermissions = []Permission{
+	PermListingsModerate,
+	PermListingsRemove,
+	PermReportsView,
+	PermReportsManage,
+	PermUsersView,
+	PermUsersSuspend,
+	PermUsersUnlock,
+}
+
+// rolePermissions maps each role to what it may do. Admins can do
+// everything moderators can and more.
+var rolePermissions = map[Role][]Permission{
+	RoleUser:      nil,
+	RoleModerator: moderatorPermissions,
+	RoleAdmin: append(append([]Permission{}, moderatorPermissions...),
+		PermListingsDelete,
+		PermUsersBan,
+		PermRolesManage,
+		PermCategoriesManage,
+		PermUploadsManage,
+		PermStatsView,
+		PermAuditView,
+	),
+}
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether the role grants permission.
+func (r Role) Can(permission Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions lists everything the role grants.
+func (r Role) Permissions() []Permission {
+	return append([]Permission{}, rolePermissions[r]...)
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+type AlertFrequency string
+
+const (
+	AlertInstant AlertFrequency = "instant" // one notification per new match
+	AlertDaily   AlertFrequency = "daily"   // matches batched into a daily digest
+	AlertOff     AlertFrequency = "off"
+)
+
+// AlertFrequencies are the accepted values for SavedSearch.Alerts.
+var AlertFrequencies = []AlertFrequency{AlertInstant, AlertDaily, AlertOff}
+
+// SavedSearch keeps a set of GetListings filters so the user can be told
+// when a new listing matches them. Nil/empty filters match everything.
+type SavedSearch struct {
+	ID           uint           `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	UserID       uint           `gorm:"index;not null" json:"user_id"`
+	Name         string         `gorm:"not null" json:"name"`
+	Search       string         `json:"search"`
+	CategoryID   *uint          `json:"category_id,omitempty"`
+	MinPrice     *float64       `json:"min_price,omitempty"`
+	MaxPrice     *float64       `json:"max_price,omitempty"`
+	Condition    string         `json:"condition"`
+	Alerts       AlertFrequency `gorm:"default:'instant'" json:"alerts"`
+	LastDigestAt *time.Time     `json:"last_digest_at,omitempty"`
+}
+
+// SavedSearchMatch records a listing that matched a saved search when it
+// was published. Daily matches wait here until the digest picks them up.
+type SavedSearchMatch struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SavedSearchID uint       `gorm:"uniqueIndex:idx_saved_search_match;not null" json:"saved_search_id"`
+	ListingID     uint       `gorm:"uniqueIndex:idx_saved_search_match;not null" json:"listing_id"`
+	Listing       Listing    `gorm:"foreignKey:ListingID" json:"listing"`
+	DigestedAt    *time.Time `gorm:"index" json:"digested_at,omitempty"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// StreamTicket lets a browser open a WebSocket or notification stream,
+// which can't carry an Authorization header, without putting its access
+// token in the URL. Tickets are spent on first use and only live a few
+// seconds; only a hash is stored.
+type StreamTicket struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	TicketHash string    `gorm:"uniqueIndex;not null"`
+	UserID     uint      `gorm:"index;not null"`
+	SessionID  uint      `gorm:"not null"`
+	ExpiresAt  time.Time `gorm:"index"`
+}
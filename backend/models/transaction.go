@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Transaction records a completed sale: who bought the listing, through
+// which chat or offer, and for how much.
+type Transaction struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	ListingID   uint      `gorm:"uniqueIndex;not null" json:"listing_id"`
+	Listing     Listing   `gorm:"foreignKey:ListingID" json:"listing"`
+	SellerID    uint      `gorm:"index;not null" json:"seller_id"`
+	Seller      User      `gorm:"foreignKey:SellerID" json:"seller"`
+	BuyerID     uint      `gorm:"index;not null" json:"buyer_id"`
+	Buyer       User      `gorm:"foreignKey:BuyerID" json:"buyer"`
+	ChatID      *uint     `json:"chat_id,omitempty"`
+	OfferID     *uint     `json:"offer_id,omitempty"`
+	ListedPrice float64   `json:"listed_price"`
+	FinalPrice  float64   `gorm:"not null" json:"final_price"`
+}
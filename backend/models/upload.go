@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Upload records an image processed by UploadImage along with the URLs of
+// every variant that was generated for it.
+type Upload struct {
+	ID           uint           `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	UserID       uint           `gorm:"not null;index" json:"user_id"`
+	URL          string         `gorm:"uniqueIndex;not null" json:"url"`
+	MediumURL    string         `json:"medium_url"`
+	ThumbnailURL string         `json:"thumbnail_url"`
+	Width        int            `json:"width"`
+	Height       int            `json:"height"`
+	MimeType     string         `json:"mime_type"`
+	Size         int64          `json:"size"` // bytes on disk across all variants
+}
@@ -18,33 +18,69 @@ type User struct {
 	ProfileImage string         `json:"profile_image"`
 	Phone        string         `json:"phone"`
 	Bio          string         `json:"bio"`
-	IsAdmin      bool           `gorm:"default:false" json:"is_admin"`
-	Listings     []Listing      `gorm:"foreignKey:SellerID" json:"listings,omitempty"`
-	Messages     []Message      `gorm:"foreignKey:SenderID" json:"messages,omitempty"`
+	Role         Role           `gorm:"default:'user';index" json:"role"`
+	// Accounts stay pending until the owner follows the emailed link
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	VerificationNonce  string     `json:"-"`
+	VerificationSentAt *time.Time `json:"-"`
+	// Set by admins; suspended accounts can sign in again after SuspendedUntil
+	SuspendedUntil   *time.Time `json:"-"`
+	BannedAt         *time.Time `json:"-"`
+	ModerationReason string     `json:"-"`
+	// Two-factor sign-in. The secret is saved at setup but only checked once
+	// TOTPEnabledAt is set
+	TOTPSecret      string     `json:"-"`
+	TOTPEnabledAt   *time.Time `json:"-"`
+	TOTPLastCounter int64      `json:"-"` // last accepted time step, so a code can't be replayed
+	TwoFactorNonce  string     `json:"-"` // binds the pending second sign-in step
+	Listings        []Listing  `gorm:"foreignKey:SellerID" json:"listings,omitempty"`
+	Messages        []Message  `gorm:"foreignKey:SenderID" json:"messages,omitempty"`
 }
 
 type UserResponse struct {
-	ID           uint      `json:"id"`
-	Email        string    `json:"email"`
-	FirstName    string    `json:"first_name"`
-	LastName     string    `json:"last_name"`
-	ProfileImage string    `json:"profile_image"`
-	Phone        string    `json:"phone"`
-	Bio          string    `json:"bio"`
-	IsAdmin      bool      `json:"is_admin"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            uint      `json:"id"`
+	Email         string    `json:"email"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	ProfileImage  string    `json:"profile_image"`
+	Phone         string    `json:"phone"`
+	Bio           string    `json:"bio"`
+	Role          Role      `json:"role"`
+	IsAdmin       bool      `json:"is_admin"` // kept for older clients; true for RoleAdmin
+	EmailVerified bool      `json:"email_verified"`
+	TwoFactor     bool      `json:"two_factor_enabled"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:           u.ID,
-		Email:        u.Email,
-		FirstName:    u.FirstName,
-		LastName:     u.LastName,
-		ProfileImage: u.ProfileImage,
-		Phone:        u.Phone,
-		Bio:          u.Bio,
-		IsAdmin:      u.IsAdmin,
-		CreatedAt:    u.CreatedAt,
+		ID:            u.ID,
+		Email:         u.Email,
+		FirstName:     u.FirstName,
+		LastName:      u.LastName,
+		ProfileImage:  u.ProfileImage,
+		Phone:         u.Phone,
+		Bio:           u.Bio,
+		Role:          u.Role,
+		IsAdmin:       u.Role == RoleAdmin,
+		EmailVerified: u.IsVerified(),
+		TwoFactor:     u.TwoFactorEnabled(),
+		CreatedAt:     u.CreatedAt,
 	}
 }
+
+func (u *User) IsVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+func (u *User) IsSuspended() bool {
+	return u.SuspendedUntil != nil && time.Now().Before(*u.SuspendedUntil)
+}
+
+func (u *User) IsBanned() bool {
+	return u.BannedAt != nil
+}
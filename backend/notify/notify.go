@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"log"
+	"strconv"
+	"uf-marketplace/database"
+	"uf-marketplace/models"
+	"uf-marketplace/realtime"
+)
+
+// Send records a notification for a single user and pushes it to their
+// open notification streams. Failures are logged rather than surfaced,
+// since a missed notification should never fail whatever triggered it.
+func Send(userID uint, notificationType models.NotificationType, title, message, link string) {
+	notification := models.Notification{
+		UserID:  userID,
+		Type:    notificationType,
+		Title:   title,
+		Message: message,
+		Link:    link,
+	}
+	if result := database.DB.Create(&notification); result.Error != nil {
+		log.Printf("Failed to create notification for user %d: %v", userID, result.Error)
+		return
+	}
+
+	realtime.NotificationBroker.Publish(userID, realtime.StreamEvent{
+		ID:   notification.ID,
+		Name: "notification",
+		Data: map[string]interface{}{"notification": notification, "unread_count": UnreadCount(userID)},
+	})
+}
+
+// PublishUnreadCount pushes the current unread count to the user's open
+// notification streams after something was read or deleted.
+func PublishUnreadCount(userID uint) {
+	realtime.NotificationBroker.Publish(userID, realtime.StreamEvent{
+		Name: "unread_count",
+		Data: map[string]interface{}{"unread_count": UnreadCount(userID)},
+	})
+}
+
+func UnreadCount(userID uint) int64 {
+	var count int64
+	database.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Count(&count)
+	return count
+}
+
+// ListingLink is the frontend path of a listing, for notification links.
+func ListingLink(listingID uint) string {
+	return "/listing/" + strconv.Itoa(int(listingID))
+}
@@ -0,0 +1,105 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	maxMessageSize = 4096
+	sendBufferSize = 64
+)
+
+// Incoming is a frame sent by the browser, e.g. a typing indicator.
+type Incoming struct {
+	Type   string `json:"type"`
+	ChatID uint   `json:"chat_id"`
+}
+
+// Client is a single WebSocket connection belonging to a user.
+type Client struct {
+	UserID    uint
+	SessionID uint
+	hub       *Hub
+	conn      *websocket.Conn
+	send      chan []byte
+}
+
+// Serve registers the connection with the hub and blocks until it closes.
+// Every frame received from the browser is passed to onMessage.
+func (h *Hub) Serve(userID, sessionID uint, conn *websocket.Conn, onMessage func(*Client, Incoming)) {
+	client := &Client{
+		UserID:    userID,
+		SessionID: sessionID,
+		hub:       h,
+		conn:      conn,
+		send:      make(chan []byte, sendBufferSize),
+	}
+	h.register(client)
+
+	go client.writePump()
+	client.readPump(onMessage)
+}
+
+func (c *Client) readPump(onMessage func(*Client, Incoming)) {
+	defer func() {
+		c.hub.unregister(c)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("WebSocket closed for user %d: %v", c.UserID, err)
+			}
+			return
+		}
+
+		var incoming Incoming
+		if err := json.Unmarshal(data, &incoming); err != nil {
+			continue
+		}
+		onMessage(c, incoming)
+	}
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case payload, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// Hub closed the channel
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// Event types pushed to chat participants
+const (
+	EventNewMessage = "message"
+	EventRead       = "read"
+	EventTyping     = "typing"
+)
+
+// Event is the envelope written to every WebSocket client.
+type Event struct {
+	Type   string      `json:"type"`
+	ChatID uint        `json:"chat_id"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// Hub tracks live connections per user. A user may hold several connections
+// at once (one per browser tab), and every one of them receives the event.
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[uint]map[*Client]struct{}
+}
+
+// ChatHub is the process-wide hub used by the chat handlers.
+var ChatHub = NewHub()
+
+func NewHub() *Hub {
+	return &Hub{clients: make(map[uint]map[*Client]struct{})}
+}
+
+func (h *Hub) register(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.clients[client.UserID] == nil {
+		h.clients[client.UserID] = make(map[*Client]struct{})
+	}
+	h.clients[client.UserID][client] = struct{}{}
+}
+
+func (h *Hub) unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	conns, ok := h.clients[client.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := conns[client]; ok {
+		delete(conns, client)
+		close(client.send)
+	}
+	if len(conns) == 0 {
+		delete(h.clients, client.UserID)
+	}
+}
+
+// Publish sends the event to every connection of every listed user. Slow
+// clients whose buffers are full are dropped rather than blocking the caller.
+func (h *Hub) Publish(userIDs []uint, event Event) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", event.Type, err)
+		return
+	}
+
+	var stale []*Client
+
+	h.mu.RLock()
+	for _, userID := range userIDs {
+		for client := range h.clients[userID] {
+			select {
+			case client.send <- payload:
+			default:
+				stale = append(stale, client)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range stale {
+		h.unregister(client)
+	}
+}
+
+// CloseSession disconnects every connection opened by the session, e.g.
+// once it has been signed out.
+func (h *Hub) CloseSession(userID, sessionID uint) {
+	var closing []*Client
+
+	h.mu.RLock()
+	for client := range h.clients[userID] {
+		if client.SessionID == sessionID {
+			closing = append(closing, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range closing {
+		h.unregister(client)
+	}
+}
+
+// IsOnline reports whether the user has at least one open connection.
+func (h *Hub) IsOnline(userID uint) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userID]) > 0
+}
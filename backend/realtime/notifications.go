@@ -0,0 +1,107 @@
+package realtime
+
+import "sync"
+
+// StreamEvent is a single Server-Sent Event queued for a subscriber. ID is
+// the notification ID when there is one, so clients can resume with
+// Last-Event-ID after a reconnect.
+type StreamEvent struct {
+	ID   uint
+	Name string
+	Data interface{}
+}
+
+// Broker fans notification events out to every open stream of a user.
+type Broker struct {
+	mu sync.RWMutex
+	// subscribers maps each user's streams to the session that opened them
+	subscribers map[uint]map[chan StreamEvent]uint
+}
+
+// NotificationBroker is the process-wide broker behind /notifications/stream.
+var NotificationBroker = NewBroker()
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[uint]map[chan StreamEvent]uint)}
+}
+
+// Subscribe opens a new stream for the user's session. The caller must
+// Unsubscribe when the client goes away, and end the stream if the broker
+// closes the channel.
+func (b *Broker) Subscribe(userID, sessionID uint) chan StreamEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan StreamEvent, sendBufferSize)
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[chan StreamEvent]uint)
+	}
+	b.subscribers[userID][ch] = sessionID
+	return ch
+}
+
+func (b *Broker) Unsubscribe(userID uint, ch chan StreamEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.remove(userID, ch)
+}
+
+// Publish queues the event on every stream the user has open. A stream
+// that has fallen behind is closed rather than silently missing events;
+// the client reconnects and catches up via Last-Event-ID.
+func (b *Broker) Publish(userID uint, event StreamEvent) {
+	var stale []chan StreamEvent
+
+	b.mu.RLock()
+	for ch := range b.subscribers[userID] {
+		select {
+		case ch <- event:
+		default:
+			stale = append(stale, ch)
+		}
+	}
+	b.mu.RUnlock()
+
+	if len(stale) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range stale {
+		if b.remove(userID, ch) {
+			close(ch)
+		}
+	}
+}
+
+// CloseSession closes every stream opened by the session, e.g. once it has
+// been signed out.
+func (b *Broker) CloseSession(userID, sessionID uint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch, subscribed := range b.subscribers[userID] {
+		if subscribed == sessionID && b.remove(userID, ch) {
+			close(ch)
+		}
+	}
+}
+
+// remove forgets the stream and reports whether it was still subscribed.
+// The caller must hold the write lock.
+func (b *Broker) remove(userID uint, ch chan StreamEvent) bool {
+	subs, ok := b.subscribers[userID]
+	if !ok {
+		return false
+	}
+	if _, ok := subs[ch]; !ok {
+		return false
+	}
+	delete(subs, ch)
+	if len(subs) == 0 {
+		delete(b.subscribers, userID)
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package realtime
+
+import "testing"
+
+func TestBrokerClosesStreamThatFallsBehind(t *testing.T) {
+	broker := NewBroker()
+	events := broker.Subscribe(1, 10)
+
+	for i := 0; i <= sendBufferSize; i++ {
+		broker.Publish(1, StreamEvent{ID: uint(i + 1), Name: "notification"})
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	if received != sendBufferSize {
+		t.Fatalf("got %d events before the stream closed, want %d", received, sendBufferSize)
+	}
+
+	// Unsubscribing after the broker closed it must not panic
+	broker.Unsubscribe(1, events)
+}
+
+func TestBrokerCloseSession(t *testing.T) {
+	broker := NewBroker()
+	revoked := broker.Subscribe(1, 10)
+	other := broker.Subscribe(1, 11)
+
+	broker.CloseSession(1, 10)
+
+	if _, ok := <-revoked; ok {
+		t.Fatal("stream of the closed session is still open")
+	}
+
+	broker.Publish(1, StreamEvent{ID: 1, Name: "notification"})
+	if event := <-other; event.ID != 1 {
+		t.Fatalf("other session got %+v", event)
+	}
+}
@@ -0,0 +1,290 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3 stores files in an S3-compatible bucket (AWS, MinIO, R2, ...). The
+// bucket can stay private: reads are served by redirecting to short-lived
+// presigned URLs. Requests are signed with AWS Signature Version 4.
+type S3 struct {
+	Endpoint  string // e.g. https://s3.us-east-1.amazonaws.com or http://localhost:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle puts the bucket in the path (endpoint/bucket/key) rather
+	// than the host name, which MinIO and most stand-ins expect
+	PathStyle bool
+
+	client *http.Client
+}
+
+// NewS3FromEnv reads S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY_ID,
+// S3_SECRET_ACCESS_KEY and S3_PATH_STYLE.
+func NewS3FromEnv() (*S3, error) {
+	store := &S3{
+		Endpoint:  strings.TrimSuffix(os.Getenv("S3_ENDPOINT"), "/"),
+		Region:    os.Getenv("S3_REGION"),
+		Bucket:    os.Getenv("S3_BUCKET"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	if store.Region == "" {
+		store.Region = "us-east-1"
+	}
+	if store.Endpoint == "" {
+		store.Endpoint = "https://s3." + store.Region + ".amazonaws.com"
+	} else {
+		// Custom endpoints are nearly always MinIO-style stand-ins
+		store.PathStyle = true
+	}
+	if pathStyle := os.Getenv("S3_PATH_STYLE"); pathStyle != "" {
+		store.PathStyle, _ = strconv.ParseBool(pathStyle)
+	}
+
+	if store.Bucket == "" || store.AccessKey == "" || store.SecretKey == "" {
+		return nil, errors.New("S3 storage requires S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY")
+	}
+	return store, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := s.request(ctx, http.MethodPut, key, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", contentType)
+	return s.do(req)
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req)
+}
+
+func (s *S3) SignedURL(key string, ttl time.Duration) (string, error) {
+	return s.presign(http.MethodGet, key, ttl, time.Now())
+}
+
+// Serve redirects to a presigned URL so the bucket itself can stay private.
+func (s *S3) Serve(w http.ResponseWriter, r *http.Request, key string) {
+	signed, err := s.SignedURL(key, DefaultSignedURLTTL)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	// Let browsers reuse the redirect, but not past the link's expiry
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(DefaultSignedURLTTL.Seconds())/2))
+	http.Redirect(w, r, signed, http.StatusFound)
+}
+
+// request builds a presigned request for the given method; presigning keeps
+// the body out of the signature so it can be streamed.
+func (s *S3) request(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	signed, err := s.presign(method, key, 5*time.Minute, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, method, signed, body)
+}
+
+// List pages through ListObjectsV2.
+func (s *S3) List(ctx context.Context) ([]Object, error) {
+	var objects []Object
+	params := map[string]string{"list-type": "2"}
+
+	for {
+		signed, err := s.presignPath(http.MethodGet, "", params, 5*time.Minute, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, signed, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("s3 list %s: %s", s.Bucket, resp.Status)
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, content := range page.Contents {
+			objects = append(objects, Object{Key: content.Key, Size: content.Size, ModTime: content.LastModified})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		params["continuation-token"] = page.NextContinuationToken
+	}
+}
+
+func (s *S3) do(req *http.Request) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// DELETE of a missing key is 204 on S3 but 404 on some stand-ins
+	if resp.StatusCode/100 == 2 || (req.Method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		return nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+// presign produces a SigV4 query-string-authenticated URL for an object.
+func (s *S3) presign(method, key string, ttl time.Duration, now time.Time) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return s.presignPath(method, key, nil, ttl, now)
+}
+
+// presignPath signs a request for key, or for the bucket itself when key is
+// empty, including any extra query params in the signature.
+func (s *S3) presignPath(method, key string, params map[string]string, ttl time.Duration, now time.Time) (string, error) {
+
+	endpoint, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	host := endpoint.Host
+	path := "/" + key
+	if s.PathStyle {
+		path = "/" + s.Bucket + path
+	} else {
+		host = s.Bucket + "." + host
+	}
+
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + s.Region + "/s3/aws4_request"
+
+	query := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    s.AccessKey + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       strconv.Itoa(int(ttl.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	for name, value := range params {
+		query[name] = value
+	}
+	canonicalQuery := canonicalQueryString(query)
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		escapePath(path),
+		canonicalQuery,
+		"host:" + host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(sha256Sum([]byte(canonicalRequest))),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.SecretKey), now.Format("20060102"))
+	signingKey = hmacSHA256(signingKey, s.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	return endpoint.Scheme + "://" + host + escapePath(path) + "?" + canonicalQuery + "&X-Amz-Signature=" + signature, nil
+}
+
+func canonicalQueryString(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = awsEscape(key) + "=" + awsEscape(params[key])
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath URI-encodes each path segment the way SigV4 expects.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsEscape is RFC 3986 percent-encoding: only A-Z a-z 0-9 - _ . ~ are left
+// as-is, which differs from url.QueryEscape in how it treats spaces and '~'.
+func awsEscape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// URLPrefix is the path every stored file is linked under. Links saved in
+// the database always use it, whichever backend is configured, so they keep
+// working if the backend changes.
+const URLPrefix = "/uploads/"
+
+// DefaultSignedURLTTL is how long redirect URLs handed out by Serve stay valid.
+const DefaultSignedURLTTL = 15 * time.Minute
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Store is a blob backend for uploaded files.
+type Store interface {
+	// Put writes the file, replacing any existing file with the same key
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes the file; deleting a missing file is not an error
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that grants read access until ttl elapses,
+	// or the plain URL for backends whose files are public
+	SignedURL(key string, ttl time.Duration) (string, error)
+	// Serve answers a request for URLPrefix + key
+	Serve(w http.ResponseWriter, r *http.Request, key string)
+	// List returns every stored file
+	List(ctx context.Context) ([]Object, error)
+}
+
+// Object describes a stored file.
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Default is the store configured by Init.
+var Default Store
+
+// Init configures Default from the environment. STORAGE_BACKEND selects
+// "local" (the default, files under ./uploads) or "s3".
+func Init() error {
+	var err error
+	switch os.Getenv("STORAGE_BACKEND") {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "./uploads"
+		}
+		Default, err = NewLocal(dir)
+	case "s3":
+		Default, err = NewS3FromEnv()
+	default:
+		err = errors.New("unknown STORAGE_BACKEND " + os.Getenv("STORAGE_BACKEND"))
+	}
+	return err
+}
+
+// URL is the app URL a stored file is linked under.
+func URL(key string) string {
+	return URLPrefix + key
+}
+
+// KeyFromURL reverses URL, reporting false for links that don't point at
+// stored files (e.g. external images).
+func KeyFromURL(url string) (string, bool) {
+	if !strings.HasPrefix(url, URLPrefix) {
+		return "", false
+	}
+	key, err := CleanKey(strings.TrimPrefix(url, URLPrefix))
+	return key, err == nil
+}
+
+// CleanKey normalises a key and rejects anything that could escape the
+// storage root.
+func CleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if key == "" || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || strings.HasPrefix(cleaned, "../") || cleaned == ".." {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
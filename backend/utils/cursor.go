@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a keyset-paginated list. It is handed to
+// clients as an opaque, signed string so they can't forge or tweak it.
+type Cursor struct {
+	ID     uint        `json:"i,omitempty"`
+	Value  interface{} `json:"v,omitempty"` // sort key of the row at ID
+	Offset int         `json:"o,omitempty"` // only for orderings that can't be keyset, e.g. relevance
+	Sort   string      `json:"s,omitempty"` // ordering the cursor was issued for
+	Before bool        `json:"b,omitempty"` // page backwards from the position
+}
+
+func EncodeCursor(cursor Cursor) string {
+	payload, _ := json.Marshal(cursor)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + Sign("cursor", encoded)
+}
+
+func DecodeCursor(value string) (Cursor, error) {
+	var cursor Cursor
+
+	encoded, signature, found := strings.Cut(value, ".")
+	if !found || !VerifySignature("cursor", encoded, signature) {
+		return cursor, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := Cursor{ID: 42, Value: "2026-01-01T00:00:00Z", Sort: "created_at:desc", Before: true}
+
+	decoded, err := DecodeCursor(EncodeCursor(cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != cursor {
+		t.Errorf("got %+v, want %+v", decoded, cursor)
+	}
+}
+
+func TestDecodeCursorRejectsTampering(t *testing.T) {
+	encoded := EncodeCursor(Cursor{ID: 42, Sort: "price:asc"})
+	payload, signature, _ := strings.Cut(encoded, ".")
+	forged, _, _ := strings.Cut(EncodeCursor(Cursor{ID: 1, Sort: "price:asc"}), ".")
+
+	tests := map[string]string{
+		"empty":             "",
+		"no signature":      payload,
+		"empty signature":   payload + ".",
+		"other payload":     forged + "." + signature,
+		"flipped signature": payload + "." + strings.ToUpper(signature),
+		"not base64":        "!!!." + Sign("cursor", "!!!"),
+		"other purpose":     payload + "." + Sign("email", payload),
+	}
+
+	for name, value := range tests {
+		if _, err := DecodeCursor(value); err != ErrInvalidCursor {
+			t.Errorf("%s: got %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
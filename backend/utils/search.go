@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// FTSMarkStart and FTSMarkEnd are what FTS5's highlight() and snippet()
+// put around matched terms. They're control characters rather than tags so
+// the listing text can be escaped before HighlightHTML turns them into
+// <mark> elements.
+const (
+	FTSMarkStart = "\x02"
+	FTSMarkEnd   = "\x03"
+)
+
+// FTSQuery turns free-text user input into a safe FTS5 MATCH expression.
+// "Quoted text" becomes a phrase query, a trailing * on a word becomes a
+// prefix query, and every other character that FTS5 treats as syntax is
+// dropped. All terms must match. Returns "" when nothing searchable is left.
+func FTSQuery(input string) string {
+	var terms []string
+
+	parts := strings.Split(input, `"`)
+	for i, part := range parts {
+		// Odd segments sit between a pair of quotes
+		if i%2 == 1 && i < len(parts)-1 {
+			if words := ftsWords(part); len(words) > 0 {
+				terms = append(terms, `"`+strings.Join(words, " ")+`"`)
+			}
+			continue
+		}
+
+		for _, field := range strings.Fields(part) {
+			prefix := strings.HasSuffix(field, "*")
+			for _, word := range ftsWords(field) {
+				terms = append(terms, `"`+word+`"`)
+			}
+			if prefix && len(terms) > 0 && !strings.HasSuffix(terms[len(terms)-1], "*") {
+				terms[len(terms)-1] += "*"
+			}
+		}
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// ftsWords splits text into runs of letters and digits.
+func ftsWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// HighlightHTML escapes FTS5 highlight() or snippet() output and wraps the
+// matched terms in <mark> tags, so it is safe to render as HTML.
+func HighlightHTML(text string) string {
+	text = html.EscapeString(text)
+	text = strings.ReplaceAll(text, FTSMarkStart, "<mark>")
+	return strings.ReplaceAll(text, FTSMarkEnd, "</mark>")
+}
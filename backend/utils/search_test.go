@@ -0,0 +1,48 @@
+package utils
+
+import "testing"
+
+func TestFTSQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"desk lamp", `"desk" "lamp"`},
+		{`"red desk" lamp`, `"red desk" "lamp"`},
+		{"mac*", `"mac"*`},
+		{"café", `"café"`},
+		{"desk OR lamp", `"desk" "OR" "lamp"`},
+		{"title:desk -lamp ^x NEAR(a b)", `"title" "desk" "lamp" "x" "NEAR" "a" "b"`},
+		{`"unclosed phrase`, `"unclosed" "phrase"`},
+		{`say "it's"`, `"say" "it s"`},
+		{"a*b", `"a" "b"`},
+		{"", ""},
+		{"   ", ""},
+		{`"" * - () :^`, ""},
+	}
+
+	for _, tt := range tests {
+		if got := FTSQuery(tt.input); got != tt.want {
+			t.Errorf("FTSQuery(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"plain text", "plain text"},
+		{"a \x02desk\x03 lamp", "a <mark>desk</mark> lamp"},
+		{"<script>\x02desk\x03</script>", "&lt;script&gt;<mark>desk</mark>&lt;/script&gt;"},
+		{"\x02<b>\x03 & \"x\"", "<mark>&lt;b&gt;</mark> &amp; &#34;x&#34;"},
+		{"<mark>fake</mark>", "&lt;mark&gt;fake&lt;/mark&gt;"},
+	}
+
+	for _, tt := range tests {
+		if got := HighlightHTML(tt.input); got != tt.want {
+			t.Errorf("HighlightHTML(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
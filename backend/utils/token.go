@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// GenerateSignedToken builds a URL-safe token binding userID and nonce
+// until ttl runs out. Callers make it single-use by rotating the nonce they
+// stored once the token has been redeemed.
+func GenerateSignedToken(purpose string, userID uint, nonce string, ttl time.Duration) string {
+	payload := fmt.Sprintf("%d.%d.%s", userID, time.Now().Add(ttl).Unix(), nonce)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + Sign(purpose, encoded)
+}
+
+// ParseSignedToken checks a token from GenerateSignedToken and returns the
+// user ID and nonce it was issued for.
+func ParseSignedToken(purpose, token string) (uint, string, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !VerifySignature(purpose, encoded, signature) {
+		return 0, "", ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, "", ErrInvalidToken
+	}
+
+	var userID uint
+	var expires int64
+	var nonce string
+	if _, err := fmt.Sscanf(string(raw), "%d.%d.%s", &userID, &expires, &nonce); err != nil {
+		return 0, "", ErrInvalidToken
+	}
+	if time.Now().Unix() > expires {
+		return 0, "", ErrInvalidToken
+	}
+
+	return userID, nonce, nil
+}
+
+// RandomString returns n random bytes encoded URL-safe.
+func RandomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// "12345678901234567890", the RFC 6238 SHA-1 test key, in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// RFC 6238 Appendix B, SHA-1 rows, cut to the last six digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := TOTPCode(rfc6238Secret, TOTPCounter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("T=%d: %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Errorf("T=%d: got %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := TOTPCounter(now)
+
+	tests := []struct {
+		offset int64
+		ok     bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, true},
+		{1, true},
+		{2, false},
+	}
+
+	for _, tt := range tests {
+		code, _ := TOTPCode(rfc6238Secret, step+tt.offset)
+		counter, ok := ValidateTOTP(rfc6238Secret, code, now)
+		if ok != tt.ok {
+			t.Errorf("step %+d: ok = %v, want %v", tt.offset, ok, tt.ok)
+		}
+		if ok && counter != step+tt.offset {
+			t.Errorf("step %+d: matched counter %d, want %d", tt.offset, counter, step+tt.offset)
+		}
+	}
+}
+
+func TestValidateTOTPInput(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	if _, ok := ValidateTOTP(rfc6238Secret, " 050 471 ", now); !ok {
+		t.Error("code with spaces was rejected")
+	}
+	for _, code := range []string{"", "05047", "0504711", "abcdef"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, now); ok {
+			t.Errorf("code %q was accepted", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", "050471", now); ok {
+		t.Error("invalid secret was accepted")
+	}
+}
@@ -12,6 +12,12 @@ export interface Message {
   created_at: string;
 }
 
+export interface MessagesPage {
+  messages: Message[];
+  next_cursor?: string;
+  prev_cursor?: string;
+}
+
 export interface Chat {
   id: number;
   listing_id: number;
@@ -22,6 +28,8 @@ export interface Chat {
   seller: User;
   last_message?: Message;
   unread_count: number;
+  muted?: boolean;
+  blocked?: boolean;
   created_at: string;
   updated_at: string;
 }
@@ -37,3 +45,9 @@ export interface Notification {
   read_at?: string;
   created_at: string;
 }
+
+export interface NotificationsPage {
+  notifications: Notification[];
+  next_cursor?: string;
+  prev_cursor?: string;
+}
@@ -5,6 +5,7 @@ export interface Category {
   name: string;
   description: string;
   icon: string;
+  listing_lifetime_days: number;
 }
 
 export interface ListingImage {
@@ -12,8 +13,14 @@ export interface ListingImage {
   listing_id: number;
   image_url: string;
   is_primary: boolean;
+  medium_url?: string;
+  thumbnail_url?: string;
+  width?: number;
+  height?: number;
 }
 
+export type ListingStatus = 'active' | 'reserved' | 'pending' | 'sold' | 'inactive' | 'removed';
+
 export interface Listing {
   id: number;
   title: string;
@@ -24,7 +31,10 @@ export interface Listing {
   seller_id: number;
   seller: User;
   images: ListingImage[];
-  status: 'active' | 'sold' | 'inactive';
+  status: ListingStatus;
+  moderation_reason?: string;
+  hidden_at?: string;
+  expires_at?: string;
   condition: string;
   location: string;
   views: number;
@@ -32,12 +42,26 @@ export interface Listing {
   updated_at: string;
 }
 
+export interface ListingStatusChange {
+  id: number;
+  listing_id: number;
+  from_status: ListingStatus | '';
+  to_status: ListingStatus;
+  actor: 'seller' | 'buyer' | 'admin' | 'system';
+  changed_by_id?: number;
+  changed_by?: User;
+  reason?: string;
+  created_at: string;
+}
+
 export interface ListingsResponse {
   listings: Listing[];
   total: number;
   page: number;
   limit: number;
   pages: number;
+  next_cursor?: string;
+  prev_cursor?: string;
 }
 
 export interface CreateListingRequest {
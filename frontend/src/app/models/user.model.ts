@@ -1,3 +1,5 @@
+export type UserRole = 'user' | 'moderator' | 'admin';
+
 export interface User {
   id: number;
   email: string;
@@ -6,7 +8,12 @@ export interface User {
   profile_image: string;
   phone: string;
   bio: string;
-  is_admin: boolean;
+  role: UserRole;
+  is_admin: boolean; // same as role === 'admin'
+  email_verified: boolean;
+  two_factor_enabled: boolean;
+  rating?: number; // average review rating, on public profiles only
+  review_count?: number;
   created_at: string;
   CreatedAt?: string; // Alternative casing from backend
 }
@@ -27,9 +34,32 @@ export function getUserInitials(user: User | null | undefined): string {
 
 export interface AuthResponse {
   token: string;
+  refresh_token: string;
+  expires_in: number;
   user: User;
 }
 
+// Login answers with this instead of an AuthResponse when the account has
+// two-factor authentication on; finish with AuthService.verifyTwoFactor.
+export interface TwoFactorChallenge {
+  two_factor_required: true;
+  two_factor_token: string;
+  expires_in: number;
+}
+
+export interface TwoFactorSetup {
+  secret: string;
+  provisioning_uri: string;
+  digits: number;
+  period: number;
+}
+
+export interface TwoFactorStatus {
+  enabled: boolean;
+  enabled_at: string | null;
+  recovery_codes_remaining: number;
+}
+
 export interface LoginRequest {
   email: string;
   password: string;
@@ -0,0 +1,44 @@
+import { Component, inject } from '@angular/core';
+import { CommonModule } from '@angular/common';
+import { FormsModule } from '@angular/forms';
+import { RouterModule } from '@angular/router';
+import { AuthService } from '../../services/auth.service';
+
+@Component({
+  selector: 'app-forgot-password',
+  standalone: true,
+  imports: [CommonModule, FormsModule, RouterModule],
+  templateUrl: './forgot-password.component.html',
+  styleUrl: '../login/login.component.scss'
+})
+export class ForgotPasswordComponent {
+  private authService = inject(AuthService);
+
+  email = '';
+  error = '';
+  message = '';
+  isLoading = false;
+
+  submit(): void {
+    this.error = '';
+    this.message = '';
+
+    if (!this.email.trim()) {
+      this.error = 'Email is required';
+      return;
+    }
+
+    this.isLoading = true;
+
+    this.authService.forgotPassword(this.email.trim().toLowerCase()).subscribe({
+      next: (response) => {
+        this.isLoading = false;
+        this.message = response.message;
+      },
+      error: (err) => {
+        this.isLoading = false;
+        this.error = err.error?.error || 'Something went wrong. Please try again.';
+      }
+    });
+  }
+}
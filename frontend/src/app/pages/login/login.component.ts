@@ -21,6 +21,10 @@ export class LoginComponent {
   error = '';
   isLoading = false;
 
+  // Set once the password is accepted for an account with two-factor on
+  twoFactorToken = '';
+  code = '';
+
   login(): void {
     this.error = '';
 
@@ -37,23 +41,61 @@ export class LoginComponent {
     this.isLoading = true;
 
     this.authService.login({ email: this.email.trim().toLowerCase(), password: this.password }).subscribe({
-      next: () => {
-        const returnUrl = this.route.snapshot.queryParams['returnUrl'] || '/';
-        this.router.navigateByUrl(returnUrl);
+      next: (response) => {
+        if ('two_factor_required' in response) {
+          this.isLoading = false;
+          this.twoFactorToken = response.two_factor_token;
+          return;
+        }
+        this.redirect();
       },
+      error: (err) => this.handleError(err)
+    });
+  }
+
+  verifyCode(): void {
+    this.error = '';
+
+    if (!this.code.trim()) {
+      this.error = 'Enter the code from your authenticator app';
+      return;
+    }
+
+    this.isLoading = true;
+
+    this.authService.verifyTwoFactor(this.twoFactorToken, this.code.trim()).subscribe({
+      next: () => this.redirect(),
       error: (err) => {
-        this.isLoading = false;
-        const errorMessage = err.error?.error;
-        if (errorMessage) {
-          this.error = errorMessage;
-        } else if (err.status === 0) {
-          this.error = 'Unable to connect to server. Please try again.';
-        } else if (err.status === 401) {
-          this.error = 'Invalid email or password.';
-        } else {
-          this.error = 'Login failed. Please try again.';
+        // The sign-in expired, so start over from the password
+        if (err.status === 401 && !err.error?.error?.startsWith('Invalid')) {
+          this.cancelTwoFactor();
         }
+        this.handleError(err);
       }
     });
   }
+
+  cancelTwoFactor(): void {
+    this.twoFactorToken = '';
+    this.code = '';
+  }
+
+  private redirect(): void {
+    const returnUrl = this.route.snapshot.queryParams['returnUrl'] || '/';
+    this.router.navigateByUrl(returnUrl);
+  }
+
+  private handleError(err: any): void {
+    this.isLoading = false;
+    const errorMessage = err.error?.error;
+    if (errorMessage) {
+      this.error = errorMessage;
+    } else if (err.status === 0) {
+      this.error = 'Unable to connect to server. Please try again.';
+    } else if (err.status === 401) {
+      this.error = 'Invalid email or password.';
+    } else {
+      this.error = 'Login failed. Please try again.';
+    }
+  }
 }
@@ -0,0 +1,57 @@
+import { Component, inject } from '@angular/core';
+import { CommonModule } from '@angular/common';
+import { FormsModule } from '@angular/forms';
+import { Router, RouterModule, ActivatedRoute } from '@angular/router';
+import { AuthService } from '../../services/auth.service';
+
+@Component({
+  selector: 'app-reset-password',
+  standalone: true,
+  imports: [CommonModule, FormsModule, RouterModule],
+  templateUrl: './reset-password.component.html',
+  styleUrl: '../login/login.component.scss'
+})
+export class ResetPasswordComponent {
+  private authService = inject(AuthService);
+  private router = inject(Router);
+  private route = inject(ActivatedRoute);
+
+  password = '';
+  confirmPassword = '';
+  error = '';
+  isLoading = false;
+
+  submit(): void {
+    this.error = '';
+
+    const token = this.route.snapshot.queryParams['token'];
+    if (!token) {
+      this.error = 'This reset link is missing its token.';
+      return;
+    }
+
+    if (this.password.length < 6) {
+      this.error = 'Password must be at least 6 characters';
+      return;
+    }
+
+    if (this.password !== this.confirmPassword) {
+      this.error = 'Passwords do not match';
+      return;
+    }
+
+    this.isLoading = true;
+
+    this.authService.resetPassword(token, this.password).subscribe({
+      next: () => {
+        // Every session was revoked server-side, including this one
+        this.authService.clearSession();
+        this.router.navigate(['/login']);
+      },
+      error: (err) => {
+        this.isLoading = false;
+        this.error = err.error?.error || 'Password reset failed. Please try again.';
+      }
+    });
+  }
+}
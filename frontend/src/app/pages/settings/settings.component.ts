@@ -109,6 +109,10 @@ export class SettingsComponent implements OnInit {
     this.router.navigate(['/login']);
   }
 
+  logoutAllDevices(): void {
+    this.authService.logoutAllDevices();
+  }
+
   updateFormField(field: string, event: Event): void {
     const value = (event.target as HTMLInputElement).value;
     this.formData.update(data => ({ ...data, [field]: value }));
@@ -0,0 +1,56 @@
+import { Component, OnInit, inject } from '@angular/core';
+import { CommonModule } from '@angular/common';
+import { RouterModule, ActivatedRoute } from '@angular/router';
+import { AuthService } from '../../services/auth.service';
+
+@Component({
+  selector: 'app-verify-email',
+  standalone: true,
+  imports: [CommonModule, RouterModule],
+  templateUrl: './verify-email.component.html',
+  styleUrl: '../login/login.component.scss'
+})
+export class VerifyEmailComponent implements OnInit {
+  private authService = inject(AuthService);
+  private route = inject(ActivatedRoute);
+
+  isLoggedIn = this.authService.isLoggedIn;
+  status: 'verifying' | 'verified' | 'failed' = 'verifying';
+  error = '';
+  message = '';
+  isResending = false;
+
+  ngOnInit(): void {
+    const token = this.route.snapshot.queryParams['token'];
+    if (!token) {
+      this.status = 'failed';
+      this.error = 'This verification link is missing its token.';
+      return;
+    }
+
+    this.authService.verifyEmail(token).subscribe({
+      next: () => {
+        this.status = 'verified';
+      },
+      error: (err) => {
+        this.status = 'failed';
+        this.error = err.error?.error || 'Verification failed. Please try again.';
+      }
+    });
+  }
+
+  resend(): void {
+    this.isResending = true;
+    this.message = '';
+    this.authService.resendVerification().subscribe({
+      next: (response) => {
+        this.isResending = false;
+        this.message = response.message;
+      },
+      error: (err) => {
+        this.isResending = false;
+        this.error = err.error?.error || 'Could not send a new link. Please try again.';
+      }
+    });
+  }
+}
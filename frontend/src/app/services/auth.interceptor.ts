@@ -1,7 +1,7 @@
 import { HttpInterceptorFn, HttpErrorResponse } from '@angular/common/http';
 import { inject } from '@angular/core';
 import { Router } from '@angular/router';
-import { catchError, throwError } from 'rxjs';
+import { catchError, switchMap, throwError } from 'rxjs';
 import { AuthService } from '../services/auth.service';
 
 export const authInterceptor: HttpInterceptorFn = (req, next) => {
@@ -19,10 +19,28 @@ export const authInterceptor: HttpInterceptorFn = (req, next) => {
 
   return next(req).pipe(
     catchError((error: HttpErrorResponse) => {
-      if (error.status === 401) {
-        authService.logout();
-        router.navigate(['/login']);
+      if (error.status !== 401 || req.url.includes('/auth/')) {
+        return throwError(() => error);
       }
+
+      // The access token is short-lived: refresh it once and retry
+      if (authService.hasRefreshToken()) {
+        return authService.refresh().pipe(
+          switchMap(response => next(req.clone({
+            setHeaders: {
+              Authorization: `Bearer ${response.token}`
+            }
+          }))),
+          catchError(refreshError => {
+            authService.clearSession();
+            router.navigate(['/login']);
+            return throwError(() => refreshError);
+          })
+        );
+      }
+
+      authService.clearSession();
+      router.navigate(['/login']);
       return throwError(() => error);
     })
   );
@@ -1,9 +1,12 @@
 import { Injectable, signal, computed } from '@angular/core';
 import { HttpClient } from '@angular/common/http';
 import { Router } from '@angular/router';
-import { Observable, tap } from 'rxjs';
+import { Observable, finalize, shareReplay, tap } from 'rxjs';
 import { environment } from '../../environments/environment';
-import { User, AuthResponse, LoginRequest, RegisterRequest } from '../models/user.model';
+import {
+  User, AuthResponse, LoginRequest, RegisterRequest,
+  TwoFactorChallenge, TwoFactorSetup, TwoFactorStatus
+} from '../models/user.model';
 
 @Injectable({
   providedIn: 'root'
@@ -12,6 +15,7 @@ export class AuthService {
   private apiUrl = environment.apiUrl;
   private currentUserSignal = signal<User | null>(null);
   private tokenSignal = signal<string | null>(null);
+  private refreshInFlight: Observable<AuthResponse> | null = null;
 
   readonly currentUser = computed(() => this.currentUserSignal());
   readonly user = this.currentUser; // Alias for convenience
@@ -37,18 +41,93 @@ export class AuthService {
     );
   }
 
-  login(data: LoginRequest): Observable<AuthResponse> {
-    return this.http.post<AuthResponse>(`${this.apiUrl}/auth/login`, data).pipe(
+  login(data: LoginRequest): Observable<AuthResponse | TwoFactorChallenge> {
+    return this.http.post<AuthResponse | TwoFactorChallenge>(`${this.apiUrl}/auth/login`, data).pipe(
+      tap(response => {
+        if ('token' in response) {
+          this.handleAuthResponse(response);
+        }
+      })
+    );
+  }
+
+  // Second sign-in step with an authenticator or recovery code
+  verifyTwoFactor(twoFactorToken: string, code: string): Observable<AuthResponse> {
+    return this.http.post<AuthResponse>(`${this.apiUrl}/auth/2fa/verify`, {
+      two_factor_token: twoFactorToken,
+      code
+    }).pipe(
       tap(response => this.handleAuthResponse(response))
     );
   }
 
-  logout(): void {
+  getTwoFactorStatus(): Observable<TwoFactorStatus> {
+    return this.http.get<TwoFactorStatus>(`${this.apiUrl}/auth/2fa`);
+  }
+
+  setupTwoFactor(password: string): Observable<TwoFactorSetup> {
+    return this.http.post<TwoFactorSetup>(`${this.apiUrl}/auth/2fa/setup`, { password });
+  }
+
+  enableTwoFactor(code: string): Observable<{ message: string; recovery_codes: string[]; user: User }> {
+    return this.http.post<{ message: string; recovery_codes: string[]; user: User }>(
+      `${this.apiUrl}/auth/2fa/enable`, { code }
+    ).pipe(
+      tap(response => this.setUser(response.user))
+    );
+  }
+
+  disableTwoFactor(password: string, code: string): Observable<{ message: string; user: User }> {
+    return this.http.post<{ message: string; user: User }>(`${this.apiUrl}/auth/2fa/disable`, { password, code }).pipe(
+      tap(response => this.setUser(response.user))
+    );
+  }
+
+  regenerateRecoveryCodes(code: string): Observable<{ recovery_codes: string[] }> {
+    return this.http.post<{ recovery_codes: string[] }>(`${this.apiUrl}/auth/2fa/recovery-codes`, { code });
+  }
+
+  // Trade the stored refresh token for a new token pair. Concurrent callers
+  // share one request since each refresh token can only be used once.
+  refresh(): Observable<AuthResponse> {
+    if (!this.refreshInFlight) {
+      this.refreshInFlight = this.http.post<AuthResponse>(`${this.apiUrl}/auth/refresh`, {
+        refresh_token: localStorage.getItem('refresh_token')
+      }).pipe(
+        tap(response => this.handleAuthResponse(response)),
+        finalize(() => this.refreshInFlight = null),
+        shareReplay(1)
+      );
+    }
+    return this.refreshInFlight;
+  }
+
+  hasRefreshToken(): boolean {
+    return !!localStorage.getItem('refresh_token');
+  }
+
+  logout(allDevices = false): void {
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (refreshToken) {
+      this.http.post(`${this.apiUrl}/auth/logout`, {
+        refresh_token: refreshToken,
+        all: allDevices
+      }).subscribe({ error: () => {} });
+    }
+    this.clearSession();
+    this.router.navigate(['/login']);
+  }
+
+  logoutAllDevices(): void {
+    this.logout(true);
+  }
+
+  clearSession(): void {
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     localStorage.removeItem('user');
     this.tokenSignal.set(null);
     this.currentUserSignal.set(null);
-    this.router.navigate(['/login']);
   }
 
   getMe(): Observable<User> {
@@ -80,8 +159,40 @@ export class AuthService {
     });
   }
 
+  verifyEmail(token: string): Observable<{ message: string; user: User }> {
+    return this.http.post<{ message: string; user: User }>(`${this.apiUrl}/auth/verify-email`, { token }).pipe(
+      tap(response => {
+        if (this.tokenSignal()) {
+          this.currentUserSignal.set(response.user);
+          localStorage.setItem('user', JSON.stringify(response.user));
+        }
+      })
+    );
+  }
+
+  resendVerification(): Observable<{ message: string }> {
+    return this.http.post<{ message: string }>(`${this.apiUrl}/auth/resend-verification`, {});
+  }
+
+  forgotPassword(email: string): Observable<{ message: string }> {
+    return this.http.post<{ message: string }>(`${this.apiUrl}/auth/forgot-password`, { email });
+  }
+
+  resetPassword(token: string, newPassword: string): Observable<{ message: string }> {
+    return this.http.post<{ message: string }>(`${this.apiUrl}/auth/reset-password`, {
+      token,
+      new_password: newPassword
+    });
+  }
+
+  private setUser(user: User): void {
+    this.currentUserSignal.set(user);
+    localStorage.setItem('user', JSON.stringify(user));
+  }
+
   private handleAuthResponse(response: AuthResponse): void {
     localStorage.setItem('token', response.token);
+    localStorage.setItem('refresh_token', response.refresh_token);
     localStorage.setItem('user', JSON.stringify(response.user));
     this.tokenSignal.set(response.token);
     this.currentUserSignal.set(response.user);
@@ -1,8 +1,8 @@
 import { Injectable, signal } from '@angular/core';
 import { HttpClient } from '@angular/common/http';
-import { Observable, tap } from 'rxjs';
+import { Observable, map, tap } from 'rxjs';
 import { environment } from '../../environments/environment';
-import { Chat, Message } from '../models/chat.model';
+import { Chat, Message, MessagesPage } from '../models/chat.model';
 
 @Injectable({
   providedIn: 'root'
@@ -29,7 +29,8 @@ export class ChatService {
   }
 
   getChatMessages(chatId: number): Observable<Message[]> {
-    return this.http.get<Message[]>(`${this.apiUrl}/chats/${chatId}/messages`).pipe(
+    return this.http.get<MessagesPage>(`${this.apiUrl}/chats/${chatId}/messages`).pipe(
+      map(page => page.messages || []),
       tap(messages => this.messages.set(messages))
     );
   }
@@ -2,7 +2,7 @@ import { Injectable } from '@angular/core';
 import { HttpClient, HttpParams } from '@angular/common/http';
 import { Observable } from 'rxjs';
 import { environment } from '../../environments/environment';
-import { Listing, ListingsResponse, Category, CreateListingRequest } from '../models/listing.model';
+import { Listing, ListingsResponse, Category, CreateListingRequest, ListingStatus, ListingStatusChange } from '../models/listing.model';
 
 export interface ListingFilters {
   search?: string;
@@ -48,6 +48,18 @@ export class ListingService {
     return this.http.put<Listing>(`${this.apiUrl}/listings/${id}`, data);
   }
 
+  updateListingStatus(id: number, status: ListingStatus, reason?: string): Observable<Listing> {
+    return this.http.put<Listing>(`${this.apiUrl}/listings/${id}/status`, { status, reason });
+  }
+
+  renewListing(id: number): Observable<Listing> {
+    return this.http.post<Listing>(`${this.apiUrl}/listings/${id}/renew`, {});
+  }
+
+  getListingHistory(id: number): Observable<ListingStatusChange[]> {
+    return this.http.get<ListingStatusChange[]>(`${this.apiUrl}/listings/${id}/history`);
+  }
+
   deleteListing(id: number): Observable<void> {
     return this.http.delete<void>(`${this.apiUrl}/listings/${id}`);
   }
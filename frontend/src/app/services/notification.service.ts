@@ -1,8 +1,8 @@
 import { Injectable, signal } from '@angular/core';
 import { HttpClient } from '@angular/common/http';
-import { Observable, tap } from 'rxjs';
+import { Observable, map, tap } from 'rxjs';
 import { environment } from '../../environments/environment';
-import { Notification } from '../models/chat.model';
+import { Notification, NotificationsPage } from '../models/chat.model';
 
 @Injectable({
   providedIn: 'root'
@@ -19,7 +19,8 @@ export class NotificationService {
     const url = unreadOnly 
       ? `${this.apiUrl}/notifications?unread=true` 
       : `${this.apiUrl}/notifications`;
-    return this.http.get<Notification[]>(url).pipe(
+    return this.http.get<NotificationsPage>(url).pipe(
+      map(page => page.notifications || []),
       tap(notifications => this.notifications.set(notifications))
     );
   }